@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// resolveTarget follows a symlinked target so the link itself is preserved and the file it
+// points to is replaced instead.
+func resolveTarget(target string) string {
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		return resolved
+	}
+	return target
+}
+
+// syncDir flushes a directory so renames inside it survive a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// ensureDir creates dir and its parents, unless in dry-run mode.
+func ensureDir(dir string) error {
+	if dryRun {
+		return nil
+	}
+	return os.MkdirAll(dir, 0755)
+}
+
+// AtomicWriteFile writes data to a temp file next to target, fsyncs it, applies mode and
+// renames it into place so readers never observe a partially written file.
+func AtomicWriteFile(target string, data []byte, mode os.FileMode) error {
+	if dryRun {
+		printDryRun("write %s (%d bytes)", target, len(data))
+		return nil
+	}
+
+	target = resolveTarget(target)
+	dir := filepath.Dir(target)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".thunderize-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := finishTemp(tmp, mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", target, err)
+	}
+	return syncDir(dir)
+}
+
+// AtomicCopyFile copies source over target atomically, preserving the source's modification
+// time. An existing target keeps its permissions; a new one gets the source's.
+func AtomicCopyFile(source, target string) error {
+	if dryRun {
+		printDryRun("copy %s to %s", source, target)
+		return nil
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", source, err)
+	}
+
+	src, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", source, err)
+	}
+	defer src.Close()
+
+	target = resolveTarget(target)
+	dir := filepath.Dir(target)
+
+	mode := info.Mode().Perm()
+	if existing, err := os.Stat(target); err == nil {
+		mode = existing.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".thunderize-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to copy %s: %w", source, err)
+	}
+	if err := finishTemp(tmp, mode); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmpPath, info.ModTime(), info.ModTime()); err != nil {
+		return fmt.Errorf("failed to set modification time: %w", err)
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", target, err)
+	}
+	return syncDir(dir)
+}
+
+// finishTemp applies mode to a temp file, fsyncs and closes it.
+func finishTemp(tmp *os.File, mode os.FileMode) error {
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmp.Name(), err)
+	}
+	return nil
+}
+
+// StagedRsync mirrors source into target through a staging copy.
+//
+// The current target is copied into a sibling staging directory, source is rsynced over
+// it, and the staged tree is swapped into place only after rsync succeeds. A failure at
+// any point before the swap leaves the previous tree untouched. The swap moves the target
+// aside before renaming the staged tree in; if a crash interrupted it, the next run puts
+// the set-aside tree back first (see recoverSwap).
+func StagedRsync(source, target string, args []string) ([]byte, error) {
+	if dryRun {
+		return runner.CombinedOutput(Command("rsync", append(args, source+"/", target+"/")...))
+	}
+
+	target = resolveTarget(target)
+	parent := filepath.Dir(target)
+	base := filepath.Base(target)
+	stage := filepath.Join(parent, "."+base+".thunderize-stage")
+	old := filepath.Join(parent, "."+base+".thunderize-old")
+
+	if err := recoverSwap(target, old); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(stage); err != nil {
+		return nil, fmt.Errorf("failed to clean staging directory: %w", err)
+	}
+	defer os.RemoveAll(stage)
+
+	_, statErr := os.Stat(target)
+	exists := statErr == nil
+
+	if exists {
+		if output, err := runner.CombinedOutput(Command("rsync", "-a", target+"/", stage+"/")); err != nil {
+			return output, fmt.Errorf("failed to stage %s: %w", target, err)
+		}
+	} else if err := os.MkdirAll(stage, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	output, err := runner.CombinedOutput(Command("rsync", append(args, source+"/", stage+"/")...))
+	if err != nil {
+		return output, fmt.Errorf("rsync failed: %w", err)
+	}
+
+	if !exists {
+		if err := os.Rename(stage, target); err != nil {
+			return output, fmt.Errorf("failed to move %s into place: %w", target, err)
+		}
+		return output, syncDir(parent)
+	}
+
+	if err := os.RemoveAll(old); err != nil {
+		return output, fmt.Errorf("failed to clean previous tree: %w", err)
+	}
+	if err := os.Rename(target, old); err != nil {
+		return output, fmt.Errorf("failed to move aside %s: %w", target, err)
+	}
+	if err := os.Rename(stage, target); err != nil {
+		if restoreErr := os.Rename(old, target); restoreErr != nil {
+			return output, fmt.Errorf("failed to swap in %s: %w (restore failed: %v)", target, err, restoreErr)
+		}
+		return output, fmt.Errorf("failed to swap in %s: %w", target, err)
+	}
+	if err := syncDir(parent); err != nil {
+		return output, err
+	}
+	if err := os.RemoveAll(old); err != nil {
+		return output, fmt.Errorf("failed to remove previous tree: %w", err)
+	}
+	return output, nil
+}
+
+// recoverSwap repairs a StagedRsync swap interrupted by a crash. When the target is missing
+// but the tree moved aside for it is still there, the old tree is restored. When both exist
+// the swap had finished and only the old tree is left to remove.
+func recoverSwap(target, old string) error {
+	if _, err := os.Stat(old); err != nil {
+		return nil
+	}
+	if _, err := os.Lstat(target); err == nil {
+		if err := os.RemoveAll(old); err != nil {
+			return fmt.Errorf("failed to remove previous tree: %w", err)
+		}
+		return nil
+	}
+
+	Print.Warn(fmt.Sprintf("Restoring %s from an interrupted sync", target))
+	if err := os.Rename(old, target); err != nil {
+		return fmt.Errorf("failed to restore %s from %s: %w", target, old, err)
+	}
+	return syncDir(filepath.Dir(target))
+}
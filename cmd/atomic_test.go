@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicCopyFileKeepsTargetMode(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	target := filepath.Join(dir, "target")
+	if err := os.WriteFile(source, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AtomicCopyFile(source, target); err != nil {
+		t.Fatalf("AtomicCopyFile: %v", err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0600 {
+		t.Errorf("target mode = %o, want 0600", got)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "new" {
+		t.Errorf("target = %q, want %q", data, "new")
+	}
+}
+
+func TestRecoverSwapRestoresMissingTarget(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "nvim")
+	old := filepath.Join(dir, ".nvim.thunderize-old")
+	if err := os.MkdirAll(old, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(old, "init.lua"), []byte("-- config"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := recoverSwap(target, old); err != nil {
+		t.Fatalf("recoverSwap: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "init.lua")); err != nil {
+		t.Errorf("target wasn't restored: %v", err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("old tree still present: %v", err)
+	}
+}
+
+func TestRecoverSwapRemovesFinishedSwap(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "nvim")
+	old := filepath.Join(dir, ".nvim.thunderize-old")
+	for _, d := range []string{target, old} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := recoverSwap(target, old); err != nil {
+		t.Fatalf("recoverSwap: %v", err)
+	}
+	if _, err := os.Stat(target); err != nil {
+		t.Errorf("target removed: %v", err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("old tree still present: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// AURHelpers are the helpers thunderize can bootstrap and drive.
+var AURHelpers = []string{"yay", "paru"}
+
+// AURHelperSpec selects the AUR helper bootstrapped when none is installed.
+type AURHelperSpec struct {
+	Name   string // yay or paru
+	Binary bool   // Install the prebuilt -bin package instead of building from source
+	Commit string // AUR git commit to build, verified after checkout ("" for the latest)
+}
+
+// Package returns the AUR package that provides the helper.
+func (s AURHelperSpec) Package() string {
+	if s.Binary {
+		return s.Name + "-bin"
+	}
+	return s.Name
+}
+
+// String formats the spec the way ParseAURHelper reads it.
+func (s AURHelperSpec) String() string {
+	if s.Commit != "" {
+		return s.Package() + "@" + s.Commit
+	}
+	return s.Package()
+}
+
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// ParseAURHelper parses a helper spec: yay, paru, yay-bin or paru-bin, optionally pinned to
+// an AUR git commit with @<commit>.
+func ParseAURHelper(spec string) (AURHelperSpec, error) {
+	var parsed AURHelperSpec
+
+	name, commit, pinned := strings.Cut(strings.TrimSpace(spec), "@")
+	if pinned {
+		if !commitPattern.MatchString(commit) {
+			return parsed, fmt.Errorf("invalid AUR helper commit %q: expected 7-40 lowercase hex characters", commit)
+		}
+		parsed.Commit = commit
+	}
+
+	parsed.Name, parsed.Binary = strings.CutSuffix(name, "-bin")
+	if !slices.Contains(AURHelpers, parsed.Name) {
+		return parsed, fmt.Errorf("unknown AUR helper %q (expected one of yay, paru, yay-bin, paru-bin)", name)
+	}
+	return parsed, nil
+}
+
+var (
+	aurHelper    = AURHelperSpec{Name: "yay"}
+	aurHelperSet bool // The helper was chosen explicitly, so another installed helper doesn't count
+	noConfirm    bool
+)
+
+// SetAURHelper sets the helper to bootstrap from a spec (see ParseAURHelper). An empty spec
+// falls back to $THUNDERIZE_AUR_HELPER and then to yay built from source.
+func SetAURHelper(spec string) error {
+	if spec == "" {
+		spec = os.Getenv("THUNDERIZE_AUR_HELPER")
+	}
+	if spec == "" {
+		aurHelper, aurHelperSet = AURHelperSpec{Name: "yay"}, false
+		return nil
+	}
+
+	parsed, err := ParseAURHelper(spec)
+	if err != nil {
+		return err
+	}
+	aurHelper, aurHelperSet = parsed, true
+	return nil
+}
+
+// SetNoConfirm sets whether the AUR helper bootstrap skips the PKGBUILD review.
+func SetNoConfirm(skip bool) {
+	noConfirm = skip
+}
+
+// InstallAURHelper installs the configured AUR helper when it isn't already installed. When no
+// helper was chosen explicitly any installed helper is accepted.
+//
+// The AUR repository is cloned into a private temporary directory that is removed afterwards.
+// A pinned commit is checked out and compared with HEAD before building, and the PKGBUILD is
+// shown for review unless --noconfirm is set.
+func InstallAURHelper() error {
+	if CheckCommandExists(aurHelper.Name) {
+		return nil
+	}
+	if !aurHelperSet && slices.ContainsFunc(AURHelpers, CheckCommandExists) {
+		return nil
+	}
+
+	pkg := aurHelper.Package()
+	Print.NewLns(StyleInfoC, fmt.Sprintf("Installing AUR helper (%s)...", pkg))
+
+	if err := runner.Run(Command("sudo", "pacman", "-S", "--needed", "--noconfirm", "base-devel", "git")); err != nil {
+		return fmt.Errorf("failed to install prerequisites: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "thunderize-aur-")
+	if err != nil {
+		return fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	buildDir := filepath.Join(tmpDir, pkg)
+
+	url := fmt.Sprintf("https://aur.archlinux.org/%s.git", pkg)
+	if err := runner.Run(Command("git", "clone", "--quiet", url, buildDir)); err != nil {
+		return fmt.Errorf("failed to clone %s: %w", pkg, err)
+	}
+
+	if IsDryRun() {
+		if aurHelper.Commit != "" {
+			printDryRun("check out and verify %s at %s", pkg, aurHelper.Commit)
+		}
+		if !noConfirm {
+			printDryRun("show %s/PKGBUILD for review", pkg)
+		}
+	} else {
+		if err := checkoutAURCommit(buildDir, aurHelper.Commit); err != nil {
+			return err
+		}
+		if !noConfirm {
+			approved, err := reviewPKGBUILD(buildDir, pkg)
+			if err != nil {
+				return err
+			}
+			if !approved {
+				return fmt.Errorf("%s build cancelled after PKGBUILD review", pkg)
+			}
+		}
+	}
+
+	if err := runner.Run(Command("makepkg", "-si", "--noconfirm").InDir(buildDir)); err != nil {
+		return fmt.Errorf("failed to build %s: %w", pkg, err)
+	}
+
+	Print.NewLns(StyleSuccess, "AUR helper installed successfully!")
+	return nil
+}
+
+// bootstrappedAURHelper returns the AUR helper to use after InstallAURHelper. In dry-run
+// mode the bootstrap only printed its commands, so the configured helper is assumed.
+func bootstrappedAURHelper() (string, error) {
+	helper, err := GetPackageManager()
+	if err != nil && IsDryRun() {
+		return aurHelper.Name, nil
+	}
+	return helper, err
+}
+
+// checkoutAURCommit checks out commit in the cloned AUR repository in dir and verifies HEAD
+// matches it. Without a commit it prints the HEAD being built so it can be pinned later.
+func checkoutAURCommit(dir, commit string) error {
+	if commit != "" {
+		if err := runner.Run(Command("git", "-c", "advice.detachedHead=false", "checkout", "--quiet", commit).InDir(dir)); err != nil {
+			return fmt.Errorf("failed to check out %s: %w", commit, err)
+		}
+	}
+
+	out, err := runner.Output(Command("git", "rev-parse", "HEAD").InDir(dir))
+	if err != nil {
+		return fmt.Errorf("failed to read checked out commit: %w", err)
+	}
+	head := strings.TrimSpace(string(out))
+
+	if commit == "" {
+		fmt.Printf("%s Building commit %s (pin it with @%s)\n", Dim("→"), head, head[:min(len(head), 12)])
+		return nil
+	}
+	if !strings.HasPrefix(head, commit) {
+		return fmt.Errorf("checked out commit %s does not match pinned commit %s", head, commit)
+	}
+	fmt.Printf("%s Verified pinned commit %s\n", Dim("→"), head)
+	return nil
+}
+
+// reviewPKGBUILD prints the PKGBUILD in dir and asks whether to build it.
+func reviewPKGBUILD(dir, pkg string) (bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "PKGBUILD"))
+	if err != nil {
+		return false, fmt.Errorf("failed to read PKGBUILD: %w", err)
+	}
+
+	Print.Beforeln(StyleInfo, fmt.Sprintf("PKGBUILD for %s:", pkg))
+	fmt.Println(Dim(strings.Repeat("─", 60)))
+	fmt.Print(string(data))
+	fmt.Println(Dim(strings.Repeat("─", 60)))
+
+	fmt.Printf("Build and install %s? (y/N): ", pkg)
+	reader := bufio.NewReader(os.Stdin)
+	resp, err := reader.ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("failed to read user input: %w", err)
+	}
+	resp = strings.ToLower(strings.TrimSpace(resp))
+	return resp == "y" || resp == "yes", nil
+}
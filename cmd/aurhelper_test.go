@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// useAURHelper bootstraps spec, without the PKGBUILD review, for the rest of the test. PATH is
+// emptied so no installed helper is found.
+func useAURHelper(t *testing.T, spec string) {
+	t.Helper()
+	previous, previousSet, previousNoConfirm := aurHelper, aurHelperSet, noConfirm
+	t.Cleanup(func() { aurHelper, aurHelperSet, noConfirm = previous, previousSet, previousNoConfirm })
+	t.Setenv("PATH", t.TempDir())
+
+	if err := SetAURHelper(spec); err != nil {
+		t.Fatal(err)
+	}
+	SetNoConfirm(true)
+}
+
+func TestInstallAURHelperBuildsPinnedCommit(t *testing.T) {
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	useAURHelper(t, "yay-bin@"+commit)
+	rec := &RecordingRunner{Respond: func(c Cmd) ([]byte, error) {
+		if slices.Contains(c.Args, "rev-parse") {
+			return []byte(commit + "\n"), nil
+		}
+		return nil, nil
+	}}
+	useRunner(t, rec)
+
+	if err := InstallAURHelper(); err != nil {
+		t.Fatalf("InstallAURHelper: %v", err)
+	}
+
+	commands := rec.Commands()
+	var got []string
+	for _, c := range commands {
+		got = append(got, c.Name+" "+strings.Join(c.Args, " "))
+	}
+	buildDir := commands[1].Args[len(commands[1].Args)-1]
+	want := []string{
+		"sudo pacman -S --needed --noconfirm base-devel git",
+		"git clone --quiet https://aur.archlinux.org/yay-bin.git " + buildDir,
+		"git -c advice.detachedHead=false checkout --quiet " + commit,
+		"git rev-parse HEAD",
+		"makepkg -si --noconfirm",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ran:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if !strings.HasSuffix(buildDir, "/yay-bin") {
+		t.Errorf("build directory %s isn't named after the package", buildDir)
+	}
+	for _, c := range commands[2:] {
+		if c.Dir != buildDir {
+			t.Errorf("%s ran in %q, want %q", c, c.Dir, buildDir)
+		}
+	}
+}
+
+func TestInstallAURHelperRejectsOtherCommit(t *testing.T) {
+	useAURHelper(t, "paru@0123456")
+	rec := &RecordingRunner{Respond: func(c Cmd) ([]byte, error) {
+		if slices.Contains(c.Args, "rev-parse") {
+			return []byte("fedcba9876543210\n"), nil
+		}
+		return nil, nil
+	}}
+	useRunner(t, rec)
+
+	err := InstallAURHelper()
+	if err == nil || !strings.Contains(err.Error(), "does not match pinned commit") {
+		t.Fatalf("InstallAURHelper = %v, want a pinned commit mismatch", err)
+	}
+	for _, c := range rec.Commands() {
+		if c.Name == "makepkg" {
+			t.Error("built the helper after a commit mismatch")
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Backend installs, removes and queries packages from one package source.
+type Backend interface {
+	// Name returns the source name used by package lists (pacman, aur, apt, ...).
+	Name() string
+	// Available reports whether the backend's tools are installed.
+	Available() bool
+	// Missing returns the packages that aren't installed yet.
+	Missing(pkgs []string) ([]string, error)
+	// Install installs packages, skipping any that are already installed.
+	Install(pkgs []string) error
+	// Remove uninstalls packages.
+	Remove(pkgs []string) error
+	// Validate returns the packages the backend can't find in its repositories, with the
+	// reason for each.
+	Validate(pkgs []string) (map[string]string, error)
+}
+
+// OSReleaseFile describes the running distribution.
+var OSReleaseFile = "/etc/os-release"
+
+// ReadOSRelease parses OSReleaseFile into its KEY=value pairs.
+func ReadOSRelease() (map[string]string, error) {
+	f, err := os.Open(OSReleaseFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", OSReleaseFile, err)
+	}
+	defer f.Close()
+
+	release := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		release[key] = strings.Trim(value, `"'`)
+	}
+	return release, scanner.Err()
+}
+
+// osSources maps os-release IDs to the source of the distribution's package manager.
+var osSources = map[string]string{
+	"arch":        SourcePacman,
+	"archarm":     SourcePacman,
+	"endeavouros": SourcePacman,
+	"manjaro":     SourcePacman,
+	"debian":      SourceApt,
+	"ubuntu":      SourceApt,
+	"fedora":      SourceDnf,
+	"rhel":        SourceDnf,
+	"centos":      SourceDnf,
+}
+
+// SystemSource returns the source of the system package manager, from the os-release ID
+// and then ID_LIKE.
+func SystemSource() (string, error) {
+	release, err := ReadOSRelease()
+	if err != nil {
+		return "", err
+	}
+
+	ids := append([]string{release["ID"]}, strings.Fields(release["ID_LIKE"])...)
+	for _, id := range ids {
+		if source, ok := osSources[id]; ok {
+			return source, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported distribution: %s", release["ID"])
+}
+
+// GetBackend returns the backend for a package list source.
+func GetBackend(source string) (Backend, error) {
+	switch source {
+	case SourcePacman:
+		return pacmanBackend{}, nil
+	case SourceAUR:
+		helper, err := GetPackageManager()
+		if err != nil {
+			return nil, err
+		}
+		return aurBackend{helper: helper}, nil
+	case SourceApt:
+		return aptBackend{}, nil
+	case SourceDnf:
+		return dnfBackend{}, nil
+	case SourceFlatpak:
+		return flatpakBackend{scope: FlatpakUser, remote: DefaultFlatpakRemote}, nil
+	}
+	return nil, fmt.Errorf("no backend for source %q", source)
+}
+
+// GetSystemBackend returns the backend of the system package manager.
+func GetSystemBackend() (Backend, error) {
+	source, err := SystemSource()
+	if err != nil {
+		return nil, err
+	}
+	backend, err := GetBackend(source)
+	if err != nil {
+		return nil, err
+	}
+	if !backend.Available() {
+		return nil, fmt.Errorf("%s is not installed", backend.Name())
+	}
+	return backend, nil
+}
+
+// runPassthrough runs a command with its output shown to the user.
+func runPassthrough(name string, args ...string) error {
+	return runner.Run(Command(name, args...))
+}
+
+// succeeds reports whether a read-only query exits with status 0, discarding its output.
+func succeeds(name string, args ...string) bool {
+	_, err := runner.Output(Command(name, args...))
+	return err == nil
+}
+
+// missingFrom returns the packages not in installed.
+func missingFrom(pkgs []string, installed map[string]bool) []string {
+	var missing []string
+	for _, pkg := range pkgs {
+		if !installed[pkg] {
+			missing = append(missing, pkg)
+		}
+	}
+	return missing
+}
+
+// invalidBy returns the packages for which known reports false, all with the same reason.
+func invalidBy(pkgs []string, known func(string) bool, reason string) map[string]string {
+	invalid := make(map[string]string)
+	for _, pkg := range pkgs {
+		if !known(pkg) {
+			invalid[pkg] = reason
+		}
+	}
+	return invalid
+}
+
+// pacmanBackend installs from the official Arch repositories.
+type pacmanBackend struct{}
+
+func (pacmanBackend) Name() string    { return SourcePacman }
+func (pacmanBackend) Available() bool { return CheckCommandExists("pacman") }
+
+func (pacmanBackend) Missing(pkgs []string) ([]string, error) {
+	index, err := LoadInstalledIndex()
+	if err != nil {
+		return nil, err
+	}
+	return FilterInstalledPackages(index, pkgs), nil
+}
+
+func (pacmanBackend) Install(pkgs []string) error {
+	return runPassthrough("sudo", append([]string{"pacman", "-S", "--needed", "--noconfirm"}, pkgs...)...)
+}
+
+func (pacmanBackend) Remove(pkgs []string) error {
+	return runPassthrough("sudo", append([]string{"pacman", "-Rns", "--noconfirm"}, pkgs...)...)
+}
+
+func (pacmanBackend) Validate(pkgs []string) (map[string]string, error) {
+	db, err := LoadSyncDB()
+	if err != nil {
+		return nil, err
+	}
+
+	invalid := make(map[string]string)
+	for _, pkg := range pkgs {
+		if db.Has(pkg) {
+			continue
+		}
+		reason := "not in the sync databases"
+		if suggestions := db.Suggest(pkg); len(suggestions) > 0 {
+			reason += ", did you mean " + strings.Join(suggestions, ", ") + "?"
+		}
+		invalid[pkg] = reason
+	}
+	return invalid, nil
+}
+
+// aurBackend installs from the AUR through yay or paru.
+type aurBackend struct {
+	helper string
+}
+
+func (aurBackend) Name() string      { return SourceAUR }
+func (b aurBackend) Available() bool { return CheckCommandExists(b.helper) }
+
+func (aurBackend) Missing(pkgs []string) ([]string, error) {
+	return pacmanBackend{}.Missing(pkgs)
+}
+
+func (b aurBackend) Install(pkgs []string) error {
+	return runPassthrough(b.helper, append([]string{"-S", "--needed", "--noconfirm"}, pkgs...)...)
+}
+
+func (aurBackend) Remove(pkgs []string) error {
+	return pacmanBackend{}.Remove(pkgs)
+}
+
+func (b aurBackend) Validate(pkgs []string) (map[string]string, error) {
+	return invalidBy(pkgs, func(pkg string) bool {
+		return succeeds(b.helper, "-Si", "--aur", pkg)
+	}, "not found in the AUR"), nil
+}
+
+// aptBackend installs with apt on Debian and Ubuntu.
+type aptBackend struct{}
+
+func (aptBackend) Name() string    { return SourceApt }
+func (aptBackend) Available() bool { return CheckCommandExists("apt-get") }
+
+func (aptBackend) Missing(pkgs []string) ([]string, error) {
+	output, err := runner.Output(Command("dpkg-query", "-W", "-f", "${Package} ${db:Status-Status}\n"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dpkg: %w", err)
+	}
+
+	installed := make(map[string]bool)
+	for line := range strings.SplitSeq(string(output), "\n") {
+		if name, status, ok := strings.Cut(line, " "); ok && status == "installed" {
+			installed[name] = true
+		}
+	}
+	return missingFrom(pkgs, installed), nil
+}
+
+func (aptBackend) Install(pkgs []string) error {
+	return runPassthrough("sudo", append([]string{"apt-get", "install", "-y", "--no-upgrade"}, pkgs...)...)
+}
+
+func (aptBackend) Remove(pkgs []string) error {
+	return runPassthrough("sudo", append([]string{"apt-get", "purge", "-y", "--autoremove"}, pkgs...)...)
+}
+
+func (aptBackend) Validate(pkgs []string) (map[string]string, error) {
+	return invalidBy(pkgs, func(pkg string) bool {
+		output, err := runner.Output(Command("apt-cache", "policy", pkg))
+		return err == nil && strings.Contains(string(output), "Candidate:") &&
+			!strings.Contains(string(output), "Candidate: (none)")
+	}, "no installation candidate in the apt sources"), nil
+}
+
+// dnfBackend installs with dnf on Fedora and RHEL.
+type dnfBackend struct{}
+
+func (dnfBackend) Name() string    { return SourceDnf }
+func (dnfBackend) Available() bool { return CheckCommandExists("dnf") }
+
+func (dnfBackend) Missing(pkgs []string) ([]string, error) {
+	output, err := runner.Output(Command("rpm", "-qa", "--qf", "%{NAME}\n"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rpm: %w", err)
+	}
+	return missingFrom(pkgs, toSet(strings.Fields(string(output)))), nil
+}
+
+func (dnfBackend) Install(pkgs []string) error {
+	return runPassthrough("sudo", append([]string{"dnf", "install", "-y"}, pkgs...)...)
+}
+
+func (dnfBackend) Remove(pkgs []string) error {
+	return runPassthrough("sudo", append([]string{"dnf", "remove", "-y"}, pkgs...)...)
+}
+
+func (dnfBackend) Validate(pkgs []string) (map[string]string, error) {
+	return invalidBy(pkgs, func(pkg string) bool {
+		return succeeds("dnf", "-q", "--cacheonly", "info", pkg)
+	}, "not found in the dnf repositories"), nil
+}
+
+// flatpakBackend installs Flatpak applications from one remote at user or system scope.
+type flatpakBackend struct {
+	scope  FlatpakScope
+	remote string
+}
+
+func (flatpakBackend) Name() string    { return SourceFlatpak }
+func (flatpakBackend) Available() bool { return CheckCommandExists("flatpak") }
+
+func (b flatpakBackend) Missing(pkgs []string) ([]string, error) {
+	output, err := runner.Output(Command("flatpak", "list", "--app", "--columns=application", b.scope.flag()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list flatpaks: %w", err)
+	}
+	return missingFrom(pkgs, toSet(strings.Fields(string(output)))), nil
+}
+
+func (b flatpakBackend) Install(pkgs []string) error {
+	args := []string{"install", "-y", "--noninteractive", b.scope.flag(), b.remote}
+	return runPassthrough("flatpak", append(args, pkgs...)...)
+}
+
+// Remove uninstalls apps from whichever installation has them, since the install record
+// doesn't keep the scope.
+func (flatpakBackend) Remove(pkgs []string) error {
+	return runPassthrough("flatpak", append([]string{"uninstall", "-y", "--noninteractive"}, pkgs...)...)
+}
+
+func (b flatpakBackend) Validate(pkgs []string) (map[string]string, error) {
+	return invalidBy(pkgs, func(pkg string) bool {
+		return succeeds("flatpak", "remote-info", b.scope.flag(), b.remote, pkg)
+	}, "not found on "+b.remote), nil
+}
+
+// isArchSource reports whether source installs through pacman's database (pacman or AUR).
+func isArchSource(source string) bool {
+	return slices.Contains([]string{SourcePacman, SourceAUR}, source)
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+)
+
+// InstallStatus is the outcome of installing a package, dev tool or tool version.
+type InstallStatus string
+
+const (
+	StatusInstalled InstallStatus = "installed"
+	StatusPresent   InstallStatus = "present"
+	StatusSkipped   InstallStatus = "skipped"
+	StatusFailed    InstallStatus = "failed"
+	StatusPlanned   InstallStatus = "planned" // Would be installed, but this is a dry run
+)
+
+// StatusCounts tallies install outcomes by status.
+type StatusCounts map[InstallStatus]int
+
+// String formats the counts as a plain summary. Planned installs only appear in dry runs.
+func (c StatusCounts) String() string {
+	plain := func(s string) string { return s }
+	return c.format(plain, plain, plain, plain)
+}
+
+// format formats the counts, styling each number with the function for its status.
+func (c StatusCounts) format(installed, present, skipped, failed func(string) string) string {
+	summary := fmt.Sprintf("%s installed, %s present, %s skipped, %s failed",
+		installed(fmt.Sprint(c[StatusInstalled])),
+		present(fmt.Sprint(c[StatusPresent])),
+		skipped(fmt.Sprint(c[StatusSkipped])),
+		failed(fmt.Sprint(c[StatusFailed])),
+	)
+	if c[StatusPlanned] > 0 {
+		summary = fmt.Sprintf("%s planned, %s", present(fmt.Sprint(c[StatusPlanned])), summary)
+	}
+	return summary
+}
+
+// printStatusSummary prints the styled totals at the end of an install.
+func printStatusSummary(counts StatusCounts) {
+	Print.Beforeln(StyleInfo, counts.format(BoldGreen, Dim, BoldYellow, BoldRed))
+}
+
+// PackageResult records what happened to a listed package and why.
+type PackageResult struct {
+	Name     string
+	Source   string
+	Optional bool
+	Status   InstallStatus
+	Reason   string
+}
+
+// installEntries installs the missing entries of a list through backend and reports the
+// outcome of every package.
+//
+// Missing packages are validated first and unknown ones are skipped. Required packages are
+// installed in one transaction; when it fails the batch is split in halves and retried until
+// the failing packages are isolated, so one renamed package doesn't block the rest. Optional
+// packages are installed one at a time and their failures don't fail the install. Installed
+// packages are recorded so prune can later remove them once they leave the lists.
+func installEntries(backend Backend, entries []PackageEntry) error {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name
+	}
+
+	missing, err := backend.Missing(names)
+	if err != nil {
+		return err
+	}
+	missingSet := toSet(missing)
+
+	if markExplicit && isArchSource(backend.Name()) {
+		index, err := LoadInstalledIndex()
+		if err != nil {
+			return err
+		}
+		if err := MarkExplicit(index.Dependencies(names)); err != nil {
+			return err
+		}
+	}
+
+	if len(missing) == 0 {
+		Print.Success("All packages already installed!")
+		return nil
+	}
+
+	invalid, err := backend.Validate(missing)
+	if err != nil {
+		Print.Warn(fmt.Sprintf("Warning: Skipping validation: %v", err))
+	}
+
+	results := make(map[string]*PackageResult, len(entries))
+	var required, optional []string
+	for _, entry := range entries {
+		result := &PackageResult{Name: entry.Name, Source: backend.Name(), Optional: entry.Optional, Status: StatusPresent}
+		results[entry.Name] = result
+
+		switch {
+		case !missingSet[entry.Name]:
+		case invalid[entry.Name] != "":
+			result.Status = StatusSkipped
+			result.Reason = invalid[entry.Name]
+		case entry.Optional:
+			optional = append(optional, entry.Name)
+		default:
+			required = append(required, entry.Name)
+		}
+	}
+
+	if len(required) > 0 {
+		fmt.Printf("%s Installing %d new packages...\n\n", Dim("→"), len(required))
+		installBatch(backend, required, results)
+	}
+
+	for _, pkg := range optional {
+		fmt.Printf("%s Installing optional package %s...\n", Dim("→"), pkg)
+		installBatch(backend, []string{pkg}, results)
+	}
+
+	var installed []string
+	for _, name := range names {
+		if results[name].Status == StatusInstalled {
+			installed = append(installed, name)
+		}
+	}
+	if err := RecordInstalled(backend.Name(), installed); err != nil {
+		Print.Warn(fmt.Sprintf("Warning: Failed to record installed packages: %v", err))
+	}
+
+	ordered := make([]PackageResult, 0, len(names))
+	for _, name := range names {
+		ordered = append(ordered, *results[name])
+	}
+	recordPackages(ordered)
+	return printInstallReport(ordered)
+}
+
+// installBatch installs pkgs in one call. When that fails it retries each half on its own,
+// down to single packages, whose failure is recorded with the command's error. In dry-run mode
+// nothing is installed, so the packages are only marked as planned.
+func installBatch(backend Backend, pkgs []string, results map[string]*PackageResult) {
+	err := backend.Install(pkgs)
+	if err == nil {
+		status := StatusInstalled
+		if IsDryRun() {
+			status = StatusPlanned
+		}
+		for _, pkg := range pkgs {
+			results[pkg].Status = status
+		}
+		return
+	}
+
+	if len(pkgs) == 1 {
+		results[pkgs[0]].Status = StatusFailed
+		results[pkgs[0]].Reason = err.Error()
+		return
+	}
+
+	mid := len(pkgs) / 2
+	Print.Warn(fmt.Sprintf("Batch of %d packages failed, retrying as %d and %d...", len(pkgs), mid, len(pkgs)-mid))
+	installBatch(backend, pkgs[:mid], results)
+	installBatch(backend, pkgs[mid:], results)
+}
+
+// printInstallReport prints every package that was installed, skipped or failed with its
+// reason, then a summary. It returns an error when a required package failed or was skipped.
+func printInstallReport(results []PackageResult) error {
+	Print.Info()
+
+	counts := make(StatusCounts)
+	blocking := 0
+	for _, result := range results {
+		counts[result.Status]++
+
+		name := result.Name
+		if result.Optional {
+			name += Dim(" (optional)")
+		}
+
+		switch result.Status {
+		case StatusInstalled:
+			fmt.Printf("  %s %s\n", BoldGreen("✓"), name)
+		case StatusPlanned:
+			fmt.Printf("  %s %s %s\n", Dim("→"), name, Dim("would install"))
+		case StatusSkipped:
+			fmt.Printf("  %s %s %s\n", BoldYellow("-"), name, Dim("skipped: "+result.Reason))
+		case StatusFailed:
+			fmt.Printf("  %s %s %s\n", BoldRed("✗"), name, Dim(result.Reason))
+		}
+
+		if !result.Optional && slices.Contains([]InstallStatus{StatusSkipped, StatusFailed}, result.Status) {
+			blocking++
+		}
+	}
+
+	printStatusSummary(counts)
+
+	if blocking > 0 {
+		return fmt.Errorf("%s could not be installed", pluralize(blocking, "required package"))
+	}
+	return nil
+}
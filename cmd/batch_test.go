@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// useLocalDB points LocalDBDir at a fixture with explicit packages and packages installed as
+// dependencies. SyncDBDir and the state directory are emptied so the host isn't read.
+func useLocalDB(t *testing.T, explicit []string, deps []string) {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(name, reason string) {
+		pkgDir := filepath.Join(dir, name+"-1.0-1")
+		if err := os.MkdirAll(pkgDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		desc := "%NAME%\n" + name + "\n\n%VERSION%\n1.0-1\n\n"
+		if reason != "" {
+			desc += "%REASON%\n" + reason + "\n\n"
+		}
+		if err := os.WriteFile(filepath.Join(pkgDir, "desc"), []byte(desc), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range explicit {
+		write(name, "")
+	}
+	for _, name := range deps {
+		write(name, "1")
+	}
+
+	oldLocal, oldSync := LocalDBDir, SyncDBDir
+	LocalDBDir, SyncDBDir = dir, t.TempDir()
+	t.Cleanup(func() { LocalDBDir, SyncDBDir = oldLocal, oldSync })
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+}
+
+func TestInstallEntriesMarksEveryDependencyExplicit(t *testing.T) {
+	useLocalDB(t, []string{"git"}, []string{"less", "perl", "zlib"})
+	rec := &RecordingRunner{}
+	useRunner(t, rec)
+	SetMarkExplicit(true)
+	t.Cleanup(func() { SetMarkExplicit(false) })
+
+	var entries []PackageEntry
+	for _, name := range []string{"perl", "git", "zlib", "less"} {
+		entries = append(entries, PackageEntry{Name: name})
+	}
+	if err := installEntries(pacmanBackend{}, entries); err != nil {
+		t.Fatalf("installEntries: %v", err)
+	}
+
+	commands := rec.Commands()
+	if len(commands) != 1 {
+		t.Fatalf("ran %d commands, want 1: %v", len(commands), commands)
+	}
+	want := []string{"pacman", "-D", "--asexplicit", "perl", "zlib", "less"}
+	if got := commands[0]; got.Name != "sudo" || !slices.Equal(got.Args, want) {
+		t.Errorf("ran %s, want sudo %v", got, want)
+	}
+}
+
+// useDryRun turns on dry-run mode for the rest of the test without replacing the runner.
+func useDryRun(t *testing.T) {
+	t.Helper()
+	dryRun = true
+	t.Cleanup(func() { dryRun = false })
+}
+
+// dnfRunner answers rpm with the installed packages and fails dnf install when it includes a
+// package in broken. Validation queries succeed.
+func dnfRunner(installed []string, broken ...string) *RecordingRunner {
+	return &RecordingRunner{Respond: func(c Cmd) ([]byte, error) {
+		switch {
+		case c.Name == "rpm":
+			return []byte(strings.Join(installed, "\n") + "\n"), nil
+		case c.Name == "sudo" && slices.ContainsFunc(c.Args, func(arg string) bool { return slices.Contains(broken, arg) }):
+			return nil, errors.New("exit status 1")
+		}
+		return nil, nil
+	}}
+}
+
+// installs returns the package arguments of every sudo dnf install in commands.
+func installs(commands []Cmd) [][]string {
+	var batches [][]string
+	for _, c := range commands {
+		if c.Name == "sudo" && slices.Equal(c.Args[:3], []string{"dnf", "install", "-y"}) {
+			batches = append(batches, c.Args[3:])
+		}
+	}
+	return batches
+}
+
+func newResults(pkgs ...string) map[string]*PackageResult {
+	results := make(map[string]*PackageResult, len(pkgs))
+	for _, pkg := range pkgs {
+		results[pkg] = &PackageResult{Name: pkg, Source: SourceDnf}
+	}
+	return results
+}
+
+func TestInstallEntriesInstallsRequiredTogetherAndOptionalAlone(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	rec := dnfRunner([]string{"git"})
+	useRunner(t, rec)
+
+	entries := []PackageEntry{{Name: "git"}, {Name: "vim"}, {Name: "tldr", Optional: true}, {Name: "htop"}}
+	if err := installEntries(dnfBackend{}, entries); err != nil {
+		t.Fatalf("installEntries: %v", err)
+	}
+
+	got := installs(rec.Commands())
+	want := [][]string{{"vim", "htop"}, {"tldr"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("installed %v, want %v", got, want)
+	}
+
+	state, err := LoadInstalledState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorded := slices.Sorted(maps.Keys(state.Packages)); !slices.Equal(recorded, []string{"htop", "tldr", "vim"}) {
+		t.Errorf("recorded %v, want htop, tldr and vim", recorded)
+	}
+}
+
+func TestInstallBatchIsolatesFailures(t *testing.T) {
+	rec := dnfRunner(nil, "broken")
+	useRunner(t, rec)
+
+	results := newResults("a", "broken", "c", "d")
+	installBatch(dnfBackend{}, []string{"a", "broken", "c", "d"}, results)
+
+	got := installs(rec.Commands())
+	want := [][]string{{"a", "broken", "c", "d"}, {"a", "broken"}, {"a"}, {"broken"}, {"c", "d"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("installed %v, want %v", got, want)
+	}
+	for pkg, status := range map[string]InstallStatus{"a": StatusInstalled, "broken": StatusFailed, "c": StatusInstalled, "d": StatusInstalled} {
+		if results[pkg].Status != status {
+			t.Errorf("%s = %s, want %s", pkg, results[pkg].Status, status)
+		}
+	}
+	if results["broken"].Reason == "" {
+		t.Error("failed package has no reason")
+	}
+}
+
+func TestInstallBatchDryRunPlansPackages(t *testing.T) {
+	useRunner(t, dnfRunner(nil))
+	useDryRun(t)
+
+	results := newResults("vim", "htop")
+	installBatch(dnfBackend{}, []string{"vim", "htop"}, results)
+
+	for _, pkg := range []string{"vim", "htop"} {
+		if results[pkg].Status != StatusPlanned {
+			t.Errorf("%s = %s in a dry run, want %s", pkg, results[pkg].Status, StatusPlanned)
+		}
+	}
+}
+
+func TestInstallEntriesDryRunRecordsNothing(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	useRunner(t, dnfRunner(nil))
+	useDryRun(t)
+
+	if err := installEntries(dnfBackend{}, []PackageEntry{{Name: "vim"}}); err != nil {
+		t.Fatalf("installEntries: %v", err)
+	}
+
+	state, err := LoadInstalledState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Packages) > 0 {
+		t.Errorf("dry run recorded %v as installed", slices.Collect(maps.Keys(state.Packages)))
+	}
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RepoManifest lists the files a dotfiles repo must contain besides the config paths in
+// AllConfigs and SecretConfigs.
+var RepoManifest = []string{
+	"packages/pacman.txt",
+	"packages/aur.txt",
+	"packages/apt.txt",
+	"packages/dnf.txt",
+	"packages/flatpak.txt",
+	"packages/dev.txt",
+	"packages/plugins.txt",
+}
+
+// BootstrapOptions controls how a new machine is bootstrapped from a git remote.
+type BootstrapOptions struct {
+	URL         string // Git remote to clone
+	Dir         string // Checkout location (~ expanded)
+	Branch      string // Branch to check out (remote default when empty)
+	SkipChecks  bool   // Skip system checks
+	SkipInstall bool   // Skip package installation
+	SkipDeploy  bool   // Skip config deployment
+}
+
+// CloneOrUpdateRepo clones url into dir, or fast-forwards an existing checkout of the same remote.
+func CloneOrUpdateRepo(url, dir, branch string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return updateRepo(url, dir, branch)
+	}
+
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s exists and is not a git checkout", dir)
+	}
+
+	Print.InfoC(fmt.Sprintf("Cloning %s...", url))
+	args := []string{"clone"}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	args = append(args, url, dir)
+
+	if err := runner.Run(Command("git", args...)); err != nil {
+		return fmt.Errorf("failed to clone %s: %w", url, err)
+	}
+	return nil
+}
+
+// updateRepo fetches and fast-forwards an existing checkout after checking its origin matches url.
+func updateRepo(url, dir, branch string) error {
+	out, err := runner.Output(Command("git", "-C", dir, "remote", "get-url", "origin"))
+	if err != nil {
+		return fmt.Errorf("failed to read origin of %s: %w", dir, err)
+	}
+	if origin := strings.TrimSpace(string(out)); !sameGitURL(origin, url) {
+		return fmt.Errorf("%s is a checkout of %s, not %s", dir, origin, url)
+	}
+
+	Print.InfoC(fmt.Sprintf("Updating %s...", dir))
+	steps := [][]string{{"fetch", "origin"}}
+	if branch != "" {
+		steps = append(steps, []string{"checkout", branch})
+	}
+	steps = append(steps, []string{"pull", "--ff-only"})
+
+	for _, args := range steps {
+		if err := runner.Run(Command("git", args...).InDir(dir)); err != nil {
+			return fmt.Errorf("git %s failed: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+// VerifyRepoManifest checks that dir contains every package list and config thunderize expects.
+func VerifyRepoManifest(dir string) error {
+	paths := append([]string{}, RepoManifest...)
+	for _, config := range append(AllConfigs, SecretConfigs...) {
+		paths = append(paths, config.RepoPath)
+	}
+
+	var missing []string
+	for _, path := range paths {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			missing = append(missing, path)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("repo at %s is missing: %s", dir, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Bootstrap clones (or updates) a dotfiles repo, records its location and runs the full setup from it.
+func Bootstrap(opts BootstrapOptions) error {
+	dir, err := ExpandPath(opts.Dir)
+	if err != nil {
+		return err
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", opts.Dir, err)
+	}
+
+	Print.NewLns(StyleInfoC, "Bootstrapping from "+opts.URL)
+	if err := RunStep("clone", func() error { return CloneOrUpdateRepo(opts.URL, dir, opts.Branch) }); err != nil {
+		return err
+	}
+
+	if err := VerifyRepoManifest(dir); err != nil {
+		return err
+	}
+	Print.Success("Repo manifest verified")
+
+	if err := RecordRepoRoot(dir); err != nil {
+		return err
+	}
+	SetRepoRoot(dir)
+	fmt.Printf("%s Recorded repo location %s\n", Dim("→"), dir)
+
+	if !opts.SkipChecks {
+		Print.Info()
+		if err := RunStep("checks", RunSystemChecks); err != nil {
+			return err
+		}
+	}
+
+	if !opts.SkipInstall {
+		Print.Info()
+		if err := RunStep("packages", func() error { return InstallAllPackages(os.DirFS(dir)) }); err != nil {
+			return err
+		}
+	}
+
+	if !opts.SkipDeploy {
+		Print.Info()
+		if err := RunStep("configs", DeployAllConfigs); err != nil {
+			return err
+		}
+	}
+
+	Print.Beforeln(StyleSuccess, "Bootstrap completed successfully!")
+	return nil
+}
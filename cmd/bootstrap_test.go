@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// git runs git in dir and fails the test on error.
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newBareRepo creates a bare repository with one commit adding name, and returns its
+// file:// URL and a work tree that pushes to it.
+func newBareRepo(t *testing.T, name string) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	root := t.TempDir()
+	bare := filepath.Join(root, "remote.git")
+	work := filepath.Join(root, "work")
+	git(t, root, "init", "--quiet", "--bare", "--initial-branch=main", bare)
+	git(t, root, "clone", "--quiet", bare, work)
+	commitFile(t, work, name)
+	return "file://" + bare, work
+}
+
+// commitFile adds name to the work tree and pushes it.
+func commitFile(t *testing.T, work, name string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(work, name), []byte(name+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, work, "add", name)
+	git(t, work, "commit", "--quiet", "-m", "add "+name)
+	git(t, work, "push", "--quiet", "origin", "HEAD:main")
+}
+
+func TestCloneOrUpdateRepoClones(t *testing.T) {
+	url, _ := newBareRepo(t, "first.txt")
+	dir := filepath.Join(t.TempDir(), "setup")
+
+	if err := CloneOrUpdateRepo(url, dir, ""); err != nil {
+		t.Fatalf("CloneOrUpdateRepo: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "first.txt")); err != nil {
+		t.Errorf("clone is missing first.txt: %v", err)
+	}
+}
+
+func TestCloneOrUpdateRepoUpdates(t *testing.T) {
+	url, work := newBareRepo(t, "first.txt")
+	dir := filepath.Join(t.TempDir(), "setup")
+	if err := CloneOrUpdateRepo(url, dir, "main"); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+
+	commitFile(t, work, "second.txt")
+
+	// The same remote spelled with a trailing slash is still the same remote.
+	if err := CloneOrUpdateRepo(url+"/", dir, "main"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "second.txt")); err != nil {
+		t.Errorf("update didn't fast-forward to second.txt: %v", err)
+	}
+}
+
+func TestCloneOrUpdateRepoRejectsOtherOrigin(t *testing.T) {
+	url, _ := newBareRepo(t, "first.txt")
+	other, _ := newBareRepo(t, "other.txt")
+	dir := filepath.Join(t.TempDir(), "setup")
+	if err := CloneOrUpdateRepo(url, dir, ""); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+
+	err := CloneOrUpdateRepo(other, dir, "")
+	if err == nil || !strings.Contains(err.Error(), "is a checkout of") {
+		t.Fatalf("CloneOrUpdateRepo with another origin = %v, want a checkout-of error", err)
+	}
+}
+
+func TestCloneOrUpdateRepoRejectsNonGitDir(t *testing.T) {
+	url, _ := newBareRepo(t, "first.txt")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CloneOrUpdateRepo(url, dir, ""); err == nil {
+		t.Fatal("CloneOrUpdateRepo into a non-empty directory succeeded")
+	}
+}
+
+func TestSameGitURL(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"https://github.com/me/setup", "https://github.com/me/setup.git", true},
+		{"https://github.com/me/setup", "https://github.com/me/setup.git/", true},
+		{"https://github.com/me/setup/", "https://github.com/me/setup", true},
+		{"https://github.com/me/setup", "https://github.com/you/setup", false},
+	}
+	for _, tt := range tests {
+		if got := sameGitURL(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameGitURL(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// writeManifest creates every file VerifyRepoManifest expects in dir.
+func writeManifest(t *testing.T, dir string) {
+	t.Helper()
+	paths := append([]string{}, RepoManifest...)
+	for _, config := range append(AllConfigs, SecretConfigs...) {
+		paths = append(paths, config.RepoPath)
+	}
+	for _, path := range paths {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// useRepoRoot restores the repo root override after the test.
+func useRepoRoot(t *testing.T) {
+	t.Helper()
+	previous := repoRootOverride
+	t.Cleanup(func() { SetRepoRoot(previous) })
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+}
+
+func TestBootstrapClonesAndRecordsRepo(t *testing.T) {
+	useRepoRoot(t)
+	const url = "https://example.com/me/setup.git"
+	dir := filepath.Join(t.TempDir(), "setup")
+	rec := &RecordingRunner{Respond: func(c Cmd) ([]byte, error) {
+		if c.Name == "git" && c.Args[0] == "clone" {
+			writeManifest(t, c.Args[len(c.Args)-1])
+		}
+		return nil, nil
+	}}
+	useRunner(t, rec)
+
+	opts := BootstrapOptions{URL: url, Dir: dir, Branch: "main", SkipChecks: true, SkipInstall: true, SkipDeploy: true}
+	if err := Bootstrap(opts); err != nil {
+		t.Fatalf("Bootstrap: %v", err)
+	}
+
+	commands := rec.Commands()
+	if len(commands) != 1 || commands[0].String() != "git clone --branch main "+url+" "+dir {
+		t.Errorf("ran %v, want one git clone --branch main", commands)
+	}
+	if root, err := GetRepoRoot(); err != nil || root != dir {
+		t.Errorf("repo root = %s (%v), want %s", root, err, dir)
+	}
+	if recorded, err := GetRecordedRepoRoot(); err != nil || recorded != dir {
+		t.Errorf("recorded repo root = %s (%v), want %s", recorded, err, dir)
+	}
+}
+
+func TestBootstrapUpdatesExistingCheckout(t *testing.T) {
+	useRepoRoot(t)
+	const url = "https://example.com/me/setup.git"
+	dir := t.TempDir()
+	writeManifest(t, dir)
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rec := &RecordingRunner{Respond: func(c Cmd) ([]byte, error) {
+		if slices.Contains(c.Args, "get-url") {
+			return []byte(strings.TrimSuffix(url, ".git") + "\n"), nil
+		}
+		return nil, nil
+	}}
+	useRunner(t, rec)
+
+	opts := BootstrapOptions{URL: url, Dir: dir, SkipChecks: true, SkipInstall: true, SkipDeploy: true}
+	if err := Bootstrap(opts); err != nil {
+		t.Fatalf("Bootstrap: %v", err)
+	}
+
+	var got []string
+	for _, c := range rec.Commands() {
+		got = append(got, c.String())
+	}
+	want := []string{"git -C " + dir + " remote get-url origin", "git fetch origin", "git pull --ff-only"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ran %v, want %v", got, want)
+	}
+}
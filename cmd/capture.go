@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CaptureSection is the section new packages are added to for review.
+const CaptureSection = "Uncategorized"
+
+// AddToSection adds pkgs to the end of the named section of a package list file, creating the
+// section at the end of the file when it doesn't exist. Existing lines, comments and headers
+// are kept as they are.
+func AddToSection(path, section string, pkgs []string) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		lines = nil
+	}
+
+	start := slices.IndexFunc(lines, func(line string) bool {
+		line = strings.TrimSpace(line)
+		return strings.HasPrefix(line, "#") && strings.EqualFold(sectionHeader(line), section)
+	})
+
+	if start < 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "# "+section)
+		lines = append(lines, pkgs...)
+	} else {
+		end := len(lines)
+		for i := start + 1; i < len(lines); i++ {
+			line := strings.TrimSpace(lines[i])
+			if strings.HasPrefix(line, "#") && sectionHeader(line) != "" {
+				end = i
+				break
+			}
+		}
+		for end > start+1 && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		lines = slices.Insert(lines, end, pkgs...)
+	}
+
+	return AtomicWriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), info.Mode().Perm())
+}
+
+// CapturePackages adds explicitly installed packages that are in no list to the repo's lists:
+// repo packages to pacman.txt and foreign (AUR) packages to aur.txt, under an "Uncategorized"
+// section for review.
+func CapturePackages() error {
+	if configSource == ConfigSourceEmbedded {
+		return fmt.Errorf("cannot capture packages into embedded package lists")
+	}
+
+	repoRoot, err := GetRepoRoot()
+	if err != nil {
+		return err
+	}
+
+	Print.NewLns(StyleInfoC, "Capturing installed packages...")
+
+	diff, err := DiffPackages(os.DirFS(repoRoot))
+	if err != nil {
+		return err
+	}
+
+	captures := []struct {
+		source string
+		pkgs   []string
+	}{
+		{SourcePacman, diff.UnlistedNative},
+		{SourceAUR, diff.UnlistedForeign},
+	}
+
+	total := 0
+	for _, capture := range captures {
+		if len(capture.pkgs) == 0 {
+			continue
+		}
+
+		file := listFileFor(capture.source)
+		if err := AddToSection(filepath.Join(repoRoot, file), CaptureSection, capture.pkgs); err != nil {
+			return err
+		}
+
+		fmt.Printf("%s Added %s to %s\n", Dim("→"), pluralize(len(capture.pkgs), "package"), file)
+		for _, pkg := range capture.pkgs {
+			fmt.Printf("  %s\n", pkg)
+		}
+		total += len(capture.pkgs)
+	}
+
+	if total == 0 {
+		Print.Success("All explicitly installed packages are already listed!")
+		return nil
+	}
+
+	Print.Beforeln(StyleSuccess, fmt.Sprintf("Captured %s", pluralize(total, "package")))
+	Print.Info(fmt.Sprintf("Review the %q sections and move entries to their proper sections.", CaptureSection))
+	return nil
+}
@@ -1,9 +1,9 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -16,18 +16,15 @@ type sysCheck struct {
 	fn   func() error
 }
 
-// CheckArchLinux verifies the system is running Arch Linux by checking for arch-release file.
-func CheckArchLinux() error {
-	if _, err := os.Stat("/etc/arch-release"); os.IsNotExist(err) {
-		return fmt.Errorf("not running on Arch Linux - /etc/arch-release not found")
-	}
-	return nil
+// CheckSupportedOS verifies the distribution's package manager is supported and installed.
+func CheckSupportedOS() error {
+	_, err := GetSystemBackend()
+	return err
 }
 
 // CheckSudoPrivileges verifies the user has sudo access without requiring a password prompt.
 func CheckSudoPrivileges() error {
-	cmd := exec.Command("sudo", "-n", "true")
-	if err := cmd.Run(); err != nil {
+	if _, err := runner.Output(Command("sudo", "-n", "true")); err != nil {
 		return fmt.Errorf("sudo privileges required - run 'sudo -v' first or configure NOPASSWD in sudoers")
 	}
 	return nil
@@ -69,7 +66,7 @@ func CheckDiskSpace() error {
 // RunSystemChecks executes all system checks and reports results.
 func RunSystemChecks() error {
 	checks := []sysCheck{
-		{"Arch Linux", CheckArchLinux},
+		{"Supported OS", CheckSupportedOS},
 		{"Sudo Privileges", CheckSudoPrivileges},
 		{"Internet Connectivity", CheckInternetConnectivity},
 		{"Disk Space", CheckDiskSpace},
@@ -107,34 +104,74 @@ func CheckCommandExists(command string) bool {
 	return err == nil
 }
 
-// GetPackageManager detects which AUR helper is installed (yay or paru).
+// GetPackageManager detects which AUR helper is installed, preferring the configured one
+// (see SetAURHelper) over yay and paru.
 func GetPackageManager() (string, error) {
-	if CheckCommandExists("yay") {
-		return "yay", nil
-	}
-	if CheckCommandExists("paru") {
-		return "paru", nil
+	for _, helper := range append([]string{aurHelper.Name}, AURHelpers...) {
+		if CheckCommandExists(helper) {
+			return helper, nil
+		}
 	}
 	return "", fmt.Errorf("no AUR helper found - install yay or paru first")
 }
 
 // GetInstalledPackages returns a list of explicitly installed packages.
 func GetInstalledPackages() ([]string, error) {
-	cmd := exec.Command("pacman", "-Qe")
-	output, err := cmd.Output()
+	return queryPacman("-Qe")
+}
+
+// GetForeignPackages returns installed packages not found in any sync database (AUR and local builds).
+func GetForeignPackages() ([]string, error) {
+	return queryPacman("-Qm")
+}
+
+// GetInstalledVersions returns the installed version of every package, keyed by name.
+func GetInstalledVersions() (map[string]string, error) {
+	rows, err := queryPacmanFields("-Q")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get installed packages: %w", err)
+		return nil, err
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	packages := make([]string, 0, len(lines))
-
-	for _, line := range lines {
-		fields := strings.Fields(line)
-		if len(fields) > 0 {
-			packages = append(packages, fields[0])
+	versions := make(map[string]string, len(rows))
+	for _, fields := range rows {
+		if len(fields) >= 2 {
+			versions[fields[0]] = fields[1]
 		}
 	}
+	return versions, nil
+}
+
+// queryPacman runs a pacman query and returns the package names from the first column.
+func queryPacman(args ...string) ([]string, error) {
+	rows, err := queryPacmanFields(args...)
+	if err != nil {
+		return nil, err
+	}
 
+	packages := make([]string, 0, len(rows))
+	for _, fields := range rows {
+		packages = append(packages, fields[0])
+	}
 	return packages, nil
 }
+
+// queryPacmanFields runs a pacman query and returns the whitespace-separated fields of each line.
+//
+// pacman exits with status 1 when a query matches nothing, which is reported as no rows.
+func queryPacmanFields(args ...string) ([][]string, error) {
+	output, err := runner.Output(Command("pacman", args...))
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 || len(output) > 0 {
+			return nil, fmt.Errorf("failed to query pacman: %w", err)
+		}
+	}
+
+	var rows [][]string
+	for line := range strings.SplitSeq(strings.TrimSpace(string(output)), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			rows = append(rows, fields)
+		}
+	}
+	return rows, nil
+}
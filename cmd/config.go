@@ -146,6 +146,9 @@ func ListConfigs() error {
 		if config.IsFile {
 			kind = "file"
 		}
+		if config.Encrypted {
+			kind += ", encrypted"
+		}
 		msg := fmt.Sprintf("  %s %s\n", BoldMagenta(config.Name), Dim(fmt.Sprintf("(%s)", kind)))
 		msg += fmt.Sprintf("    %s %s\n", Dim("Repo:"), config.RepoPath)
 		msg += fmt.Sprintf("    %s %s\n", Dim("System:"), config.SystemPath)
@@ -154,7 +157,7 @@ func ListConfigs() error {
 	return nil
 }
 
-// ValidateConfigs checks that all config files exist in the repo.
+// ValidateConfigs checks that all config files exist in the repo or the embedded config tree.
 func ValidateConfigs() error {
 	Print.NewLns(StyleInfoC, "Validating configurations...")
 
@@ -166,11 +169,21 @@ func ValidateConfigs() error {
 		}
 
 		fmt.Printf("  %s ", config.Name)
-		if _, err := os.Stat(repoPath); err != nil {
-			Print.Err("✗ (missing)")
+		_, statErr := os.Stat(repoPath)
+		switch {
+		case config.Encrypted && statErr == nil && !isEncryptedFile(repoPath):
+			Print.Err("✗ (plaintext in repo, must be encrypted)")
 			isOk = false
-		} else {
+		case config.Encrypted && !config.IsFile:
+			Print.Err("✗ (only single files can be encrypted)")
+			isOk = false
+		case statErr == nil && configSource != ConfigSourceEmbedded:
 			Print.Success("✓")
+		case configSource != ConfigSourceRepo && config.HasEmbedded():
+			Print.Success("✓ (embedded)")
+		default:
+			Print.Err("✗ (missing)")
+			isOk = false
 		}
 	}
 
@@ -179,5 +192,11 @@ func ValidateConfigs() error {
 		return nil
 	}
 	Print.Info()
-	return fmt.Errorf("some configurations are missing from the repo")
+	return fmt.Errorf("some configurations are missing from the repo or invalid")
+}
+
+// isEncryptedFile reports whether the file at path is age-encrypted.
+func isEncryptedFile(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && IsEncrypted(data)
 }
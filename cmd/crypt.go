@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"golang.org/x/term"
+)
+
+const (
+	ageHeader      = "age-encryption.org/v1"
+	ageArmorHeader = armor.Header
+)
+
+// agePassphrase is the passphrase that last decrypted or encrypted a config, reused for the
+// rest of the run.
+var agePassphrase string
+
+// GetAgeKeyFile returns the age identity file used for encrypted configs, or "" if none is configured.
+//
+// $THUNDERIZE_AGE_KEY_FILE takes precedence over the default $XDG_CONFIG_HOME/thunderize/age.key.
+func GetAgeKeyFile() (string, error) {
+	if path := os.Getenv("THUNDERIZE_AGE_KEY_FILE"); path != "" {
+		return ExpandPath(path)
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", nil
+	}
+	path := filepath.Join(configDir, "thunderize", "age.key")
+	if _, err := os.Stat(path); err != nil {
+		return "", nil
+	}
+	return path, nil
+}
+
+// getPassphrase returns the passphrase from $THUNDERIZE_AGE_PASSPHRASE, or the one that already
+// worked this run, prompting otherwise. With confirm, as when encrypting, the prompt asks twice
+// so a typo can't lock the file. A prompted passphrase is only remembered once it has worked.
+func getPassphrase(confirm bool) (string, error) {
+	if agePassphrase != "" {
+		return agePassphrase, nil
+	}
+	if pass := os.Getenv("THUNDERIZE_AGE_PASSPHRASE"); pass != "" {
+		return pass, nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("no age key file or passphrase configured - set THUNDERIZE_AGE_KEY_FILE or THUNDERIZE_AGE_PASSPHRASE")
+	}
+
+	pass, err := readPassphrase("Passphrase for encrypted configs: ")
+	if err != nil {
+		return "", err
+	}
+	if len(pass) == 0 {
+		return "", fmt.Errorf("empty passphrase")
+	}
+	if confirm {
+		again, err := readPassphrase("Confirm passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != pass {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	return pass, nil
+}
+
+// readPassphrase prompts for a passphrase without echoing it.
+func readPassphrase(prompt string) (string, error) {
+	fmt.Print(prompt)
+	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(pass), nil
+}
+
+// loadIdentities returns the identities for decrypting configs from the key file or a passphrase,
+// and the passphrase ("" with a key file).
+func loadIdentities() ([]age.Identity, string, error) {
+	keyFile, err := GetAgeKeyFile()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if keyFile != "" {
+		f, err := os.Open(keyFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open age key file: %w", err)
+		}
+		defer f.Close()
+
+		identities, err := age.ParseIdentities(f)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse age key file %s: %w", keyFile, err)
+		}
+		return identities, "", nil
+	}
+
+	pass, err := getPassphrase(false)
+	if err != nil {
+		return nil, "", err
+	}
+	identity, err := age.NewScryptIdentity(pass)
+	if err != nil {
+		return nil, "", err
+	}
+	return []age.Identity{identity}, pass, nil
+}
+
+// loadRecipients returns the recipients for encrypting configs from the key file or a passphrase,
+// and the passphrase ("" with a key file).
+func loadRecipients() ([]age.Recipient, string, error) {
+	keyFile, err := GetAgeKeyFile()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if keyFile != "" {
+		identities, _, err := loadIdentities()
+		if err != nil {
+			return nil, "", err
+		}
+
+		var recipients []age.Recipient
+		for _, identity := range identities {
+			if x, ok := identity.(*age.X25519Identity); ok {
+				recipients = append(recipients, x.Recipient())
+			}
+		}
+		if len(recipients) == 0 {
+			return nil, "", fmt.Errorf("age key file %s contains no X25519 identities", keyFile)
+		}
+		return recipients, "", nil
+	}
+
+	pass, err := getPassphrase(true)
+	if err != nil {
+		return nil, "", err
+	}
+	recipient, err := age.NewScryptRecipient(pass)
+	if err != nil {
+		return nil, "", err
+	}
+	return []age.Recipient{recipient}, pass, nil
+}
+
+// IsEncrypted reports whether data is an age file, binary or ASCII-armored.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(ageHeader)) ||
+		bytes.HasPrefix(bytes.TrimSpace(data), []byte(ageArmorHeader))
+}
+
+// EncryptConfig encrypts plaintext as an ASCII-armored age file so it diffs cleanly in git.
+func EncryptConfig(plaintext []byte) ([]byte, error) {
+	recipients, pass, err := loadRecipients()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	armored := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armored, recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if err := armored.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if pass != "" {
+		agePassphrase = pass
+	}
+	return buf.Bytes(), nil
+}
+
+// DecryptConfig decrypts an age file, binary or ASCII-armored.
+func DecryptConfig(ciphertext []byte) ([]byte, error) {
+	if !IsEncrypted(ciphertext) {
+		return nil, fmt.Errorf("not an age-encrypted file")
+	}
+
+	identities, pass, err := loadIdentities()
+	if err != nil {
+		return nil, err
+	}
+
+	var src io.Reader = bytes.NewReader(ciphertext)
+	if !bytes.HasPrefix(ciphertext, []byte(ageHeader)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(ciphertext)))
+	}
+
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	if pass != "" {
+		agePassphrase = pass
+	}
+	return plaintext, nil
+}
+
+// syncEncrypted deploys or backs up an encrypted config, decrypting or encrypting on the way,
+// and reports whether the target was left untouched because it already matched.
+//
+// Deploys are written with 0600 permissions. Backups leave the repo file untouched when its
+// decrypted contents already match the system file, so re-encryption doesn't churn git, and
+// refuse to overwrite a repo file they can't decrypt, so a mistyped passphrase can't replace
+// the secret.
+func syncEncrypted(config *ConfigType, source, target string, toSystem bool) (bool, error) {
+	if !config.IsFile {
+		return false, fmt.Errorf("%s: only single-file configs can be encrypted", config.Name)
+	}
+
+	if err := ensureDir(filepath.Dir(target)); err != nil {
+		return false, fmt.Errorf("failed to create target directory: %w", err)
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+
+	if toSystem {
+		Print.InfoC(fmt.Sprintf("Deploying %s config (encrypted)...", config.Name))
+		fmt.Printf("%s %s\n", Dim("Source:"), source)
+		fmt.Printf("%s %s\n", Dim("Target:"), target)
+
+		plaintext, err := DecryptConfig(data)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", config.Name, err)
+		}
+		if info, err := os.Stat(target); err == nil && info.Mode().Perm() == 0600 && fileHasContents(target, plaintext) {
+			Print.Success(fmt.Sprintf("%s config unchanged", config.Name))
+			return true, nil
+		}
+		if err := AtomicWriteFile(target, plaintext, 0600); err != nil {
+			return false, err
+		}
+		Print.Success(fmt.Sprintf("%s config deployed successfully", config.Name))
+		return false, nil
+	}
+
+	Print.InfoC(fmt.Sprintf("Backing up %s config (encrypted)...", config.Name))
+	fmt.Printf("%s %s\n", Dim("Source:"), source)
+	fmt.Printf("%s %s\n", Dim("Target:"), target)
+
+	if IsEncrypted(data) {
+		return false, fmt.Errorf("%s: system file is already encrypted, refusing to double-encrypt", config.Name)
+	}
+
+	if existing, err := os.ReadFile(target); err == nil && IsEncrypted(existing) {
+		current, err := DecryptConfig(existing)
+		if err != nil {
+			return false, fmt.Errorf("%s: refusing to overwrite %s: %w", config.Name, target, err)
+		}
+		if bytes.Equal(current, data) {
+			Print.Success(fmt.Sprintf("%s config unchanged", config.Name))
+			return true, nil
+		}
+	}
+
+	ciphertext, err := EncryptConfig(data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", config.Name, err)
+	}
+	if err := AtomicWriteFile(target, ciphertext, 0644); err != nil {
+		return false, err
+	}
+	Print.Success(fmt.Sprintf("%s config backed up successfully", config.Name))
+	return false, nil
+}
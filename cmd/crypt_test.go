@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// usePassphrase encrypts with pass from $THUNDERIZE_AGE_PASSPHRASE for the rest of the test,
+// with no key file and nothing remembered from earlier tests.
+func usePassphrase(t *testing.T, pass string) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("THUNDERIZE_AGE_KEY_FILE", "")
+	t.Setenv("THUNDERIZE_AGE_PASSPHRASE", pass)
+	agePassphrase = ""
+	t.Cleanup(func() { agePassphrase = "" })
+}
+
+// encryptedConfig returns a single-file encrypted config with a system file holding data and
+// the path of its repo copy.
+func encryptedConfig(t *testing.T, data string) (*ConfigType, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	system := filepath.Join(dir, "netrc")
+	if err := os.WriteFile(system, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := &ConfigType{Name: "netrc", IsFile: true, Encrypted: true}
+	return config, system, filepath.Join(dir, "repo", "netrc.age")
+}
+
+func TestSyncEncryptedRoundTrip(t *testing.T) {
+	usePassphrase(t, "correct horse")
+	config, system, repo := encryptedConfig(t, "machine example.com password hunter2\n")
+
+	if _, err := syncEncrypted(config, system, repo, false); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	ciphertext, err := os.ReadFile(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(ciphertext) || bytes.Contains(ciphertext, []byte("hunter2")) {
+		t.Fatalf("repo copy isn't encrypted:\n%s", ciphertext)
+	}
+
+	deployed := filepath.Join(t.TempDir(), "netrc")
+	if _, err := syncEncrypted(config, repo, deployed, true); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if got, _ := os.ReadFile(deployed); string(got) != "machine example.com password hunter2\n" {
+		t.Errorf("deployed %q", got)
+	}
+	if info, err := os.Stat(deployed); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("deployed mode = %v (%v), want 0600", info.Mode().Perm(), err)
+	}
+
+	if unchanged, err := syncEncrypted(config, repo, deployed, true); err != nil || !unchanged {
+		t.Errorf("second deploy = %v, %v, want unchanged", unchanged, err)
+	}
+}
+
+func TestSyncEncryptedSkipsUnchangedFile(t *testing.T) {
+	usePassphrase(t, "correct horse")
+	config, system, repo := encryptedConfig(t, "token\n")
+	if _, err := syncEncrypted(config, system, repo, false); err != nil {
+		t.Fatalf("first backup: %v", err)
+	}
+	first, _ := os.ReadFile(repo)
+
+	if unchanged, err := syncEncrypted(config, system, repo, false); err != nil || !unchanged {
+		t.Fatalf("second backup = %v, %v, want unchanged", unchanged, err)
+	}
+	// age encryption is randomized, so any rewrite would change the bytes.
+	if second, _ := os.ReadFile(repo); !bytes.Equal(first, second) {
+		t.Error("unchanged config was re-encrypted")
+	}
+}
+
+func TestSyncEncryptedKeepsFileItCantDecrypt(t *testing.T) {
+	usePassphrase(t, "correct horse")
+	config, system, repo := encryptedConfig(t, "old secret\n")
+	if _, err := syncEncrypted(config, system, repo, false); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	original, _ := os.ReadFile(repo)
+
+	usePassphrase(t, "corect horse")
+	if err := os.WriteFile(system, []byte("new secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := syncEncrypted(config, system, repo, false)
+	if err == nil || !strings.Contains(err.Error(), "refusing to overwrite") {
+		t.Fatalf("backup with a wrong passphrase = %v, want a refusal", err)
+	}
+	if current, _ := os.ReadFile(repo); !bytes.Equal(original, current) {
+		t.Error("repo copy was overwritten after a failed decrypt")
+	}
+	if agePassphrase != "" {
+		t.Error("wrong passphrase was remembered")
+	}
+}
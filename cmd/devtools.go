@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DevTool is a language dev tool listed in packages/dev.txt.
+type DevTool struct {
+	Name      string // Display name (e.g., "delve")
+	Installer string // Installer key (e.g., "go", "pipx")
+	Package   string // Package passed to the installer (defaults to Name)
+	Binary    string // Command used to detect an existing install (defaults to Name)
+}
+
+// DevToolResult records what happened to a dev tool and why.
+type DevToolResult struct {
+	Tool   DevTool
+	Status InstallStatus
+	Reason string
+}
+
+// devInstaller describes how to install a package with a given tool.
+type devInstaller struct {
+	requires string   // Command that must be on PATH
+	args     []string // Command line, the package is appended
+}
+
+// devInstallers maps the via= attribute in dev.txt to its installer.
+var devInstallers = map[string]devInstaller{
+	"pipx":   {"pipx", []string{"pipx", "install"}},
+	"go":     {"go", []string{"go", "install"}},
+	"cargo":  {"cargo", []string{"cargo", "install"}},
+	"rustup": {"rustup", []string{"rustup", "component", "add"}},
+	"npm":    {"npm", []string{"npm", "install", "-g"}},
+	"opam":   {"opam", []string{"opam", "install", "-y"}},
+	"dotnet": {"dotnet", []string{"dotnet", "tool", "install", "--global"}},
+	"pacman": {"pacman", []string{"sudo", "pacman", "-S", "--needed", "--noconfirm"}},
+}
+
+// ReadDevTools parses packages/dev.txt into dev tools.
+func ReadDevTools(fsys fs.FS) ([]DevTool, error) {
+	list, err := ParsePackageList(fsys, "packages/dev.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	var tools []DevTool
+	for _, entry := range list.Entries() {
+		if !entry.Applies() {
+			continue
+		}
+
+		name := entry.Name
+		tool := DevTool{
+			Name:      name,
+			Installer: entry.Attrs["via"],
+			Package:   entry.Attrs["pkg"],
+			Binary:    entry.Attrs["bin"],
+		}
+		if tool.Installer == "" {
+			return nil, fmt.Errorf("dev tool %s has no installer (via=)", name)
+		}
+		if _, ok := devInstallers[tool.Installer]; !ok {
+			return nil, fmt.Errorf("dev tool %s has unknown installer: %s", name, tool.Installer)
+		}
+		if tool.Package == "" {
+			tool.Package = name
+		}
+		if tool.Binary == "" {
+			tool.Binary = name
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// devToolDirs returns install locations that may not be on PATH yet in a fresh shell.
+func devToolDirs() []string {
+	homeDir, err := GetHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	dirs := []string{
+		filepath.Join(homeDir, "go", "bin"),
+		filepath.Join(homeDir, ".cargo", "bin"),
+		filepath.Join(homeDir, ".dotnet", "tools"),
+		filepath.Join(homeDir, ".local", "bin"),
+		filepath.Join(homeDir, ".opam", "default", "bin"),
+	}
+	if gobin := os.Getenv("GOBIN"); gobin != "" {
+		dirs = append(dirs, gobin)
+	}
+	if prefix := os.Getenv("OPAM_SWITCH_PREFIX"); prefix != "" {
+		dirs = append(dirs, filepath.Join(prefix, "bin"))
+	}
+	return dirs
+}
+
+// IsDevToolInstalled reports whether the tool's command is on PATH or in a known install location.
+// rustup components are checked with rustup itself, since rustup installs a proxy for them
+// (rust-analyzer) whether or not the component is installed.
+func IsDevToolInstalled(tool DevTool) bool {
+	if tool.Installer == "rustup" {
+		return rustupComponentInstalled(tool.Package)
+	}
+	if CheckCommandExists(tool.Binary) {
+		return true
+	}
+	for _, dir := range devToolDirs() {
+		if info, err := os.Stat(filepath.Join(dir, tool.Binary)); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// targetTriple matches a Rust target such as x86_64-unknown-linux-gnu or aarch64-apple-darwin.
+var targetTriple = regexp.MustCompile(`^[a-z0-9_]+(-[a-z0-9_]+){2,3}$`)
+
+// rustupComponentInstalled reports whether rustup lists component as installed. Installed
+// components carry their target triple (rust-analyzer-x86_64-unknown-linux-gnu).
+func rustupComponentInstalled(component string) bool {
+	out, err := runner.Output(Command("rustup", "component", "list", "--installed"))
+	if err != nil {
+		return false
+	}
+	for installed := range strings.SplitSeq(string(out), "\n") {
+		installed = strings.TrimSpace(installed)
+		if installed == component {
+			return true
+		}
+		if target, ok := strings.CutPrefix(installed, component+"-"); ok && targetTriple.MatchString(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// InstallDevTool installs a single dev tool, skipping it when already present.
+func InstallDevTool(tool DevTool) DevToolResult {
+	if IsDevToolInstalled(tool) {
+		return DevToolResult{Tool: tool, Status: StatusPresent}
+	}
+
+	installer := devInstallers[tool.Installer]
+	if !CheckCommandExists(installer.requires) {
+		return DevToolResult{Tool: tool, Status: StatusSkipped, Reason: installer.requires + " not found"}
+	}
+
+	args := append(append([]string{}, installer.args...), tool.Package)
+	output, err := runner.CombinedOutput(Command(args[0], args[1:]...))
+	if err != nil {
+		return DevToolResult{Tool: tool, Status: StatusFailed, Reason: lastLine(output, err)}
+	}
+	if IsDryRun() {
+		return DevToolResult{Tool: tool, Status: StatusPlanned}
+	}
+	return DevToolResult{Tool: tool, Status: StatusInstalled}
+}
+
+// lastLine returns the last non-empty line of command output, or err ("" when nil) when there
+// is none.
+func lastLine(output []byte, err error) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return last
+	}
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// InstallDevPackages installs the language dev tools listed in packages/dev.txt.
+func InstallDevPackages(fsys fs.FS) error {
+	Print.NewLns(StyleInfoC, "Installing language dev tools...")
+
+	tools, err := ReadDevTools(fsys)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s Found %d tools in list\n\n", Dim("→"), len(tools))
+
+	counts := make(StatusCounts)
+	for _, tool := range tools {
+		fmt.Printf("  %s %s ", tool.Name, Dim("("+tool.Installer+")"))
+		result := InstallDevTool(tool)
+		counts[result.Status]++
+
+		switch result.Status {
+		case StatusInstalled:
+			Print.Success("✓ installed")
+		case StatusPresent:
+			Print.Dimmed("✓ already installed")
+		case StatusPlanned:
+			Print.Dimmed("→ would install")
+		case StatusSkipped:
+			Print.Warn("- skipped: " + result.Reason)
+		case StatusFailed:
+			Print.Err("✗ " + result.Reason)
+		}
+	}
+
+	printStatusSummary(counts)
+
+	if counts[StatusFailed] > 0 {
+		return fmt.Errorf("%d dev tools failed to install", counts[StatusFailed])
+	}
+
+	Print.Beforeln(StyleSuccess, "Language dev tools installed successfully!")
+	return nil
+}
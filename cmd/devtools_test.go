@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+// useRunner installs r as the runner for the rest of the test.
+func useRunner(t *testing.T, r Runner) {
+	t.Helper()
+	previous := runner
+	SetRunner(r)
+	t.Cleanup(func() { SetRunner(previous) })
+}
+
+func TestRustupComponentInstalled(t *testing.T) {
+	useRunner(t, &RecordingRunner{Respond: func(c Cmd) ([]byte, error) {
+		return []byte("cargo-x86_64-unknown-linux-gnu\nrust-analyzer-x86_64-unknown-linux-gnu\nrustc-x86_64-unknown-linux-gnu\n"), nil
+	}})
+
+	if !rustupComponentInstalled("rust-analyzer") {
+		t.Error("rust-analyzer not detected")
+	}
+	if rustupComponentInstalled("clippy") {
+		t.Error("clippy detected but not installed")
+	}
+	if rustupComponentInstalled("rust") {
+		t.Error("rust matched rust-analyzer by prefix")
+	}
+}
+
+func TestRustupProxyIsNotAnInstall(t *testing.T) {
+	useRunner(t, &RecordingRunner{Respond: func(c Cmd) ([]byte, error) {
+		return nil, errors.New("rustup: command not found")
+	}})
+
+	tool := DevTool{Name: "rust-analyzer", Installer: "rustup", Package: "rust-analyzer", Binary: "sh"}
+	if IsDevToolInstalled(tool) {
+		t.Error("rustup component reported installed because its binary is on PATH")
+	}
+}
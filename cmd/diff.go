@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+)
+
+// MisplacedPackage is a listed package whose install origin doesn't match its list.
+type MisplacedPackage struct {
+	Name      string `json:"name"`
+	ListedIn  string `json:"listed_in"`
+	BelongsIn string `json:"belongs_in"`
+}
+
+// PackageDiff compares the package lists with what is installed on the system.
+type PackageDiff struct {
+	Missing         []string           `json:"missing"`          // Listed for this machine but not installed
+	UnlistedNative  []string           `json:"unlisted_native"`  // Explicitly installed from a repo but in no list
+	UnlistedForeign []string           `json:"unlisted_foreign"` // Explicitly installed foreign (AUR) packages in no list
+	Misplaced       []MisplacedPackage `json:"misplaced"`        // Installed from a different source than their list says
+}
+
+// listFileFor returns the package list holding entries for source by default.
+func listFileFor(source string) string {
+	for _, file := range PackageListFiles {
+		if file.Source == source {
+			return file.File
+		}
+	}
+	return source
+}
+
+// DiffPackages compares the pacman and AUR package lists against the installed system.
+func DiffPackages(fsys fs.FS) (*PackageDiff, error) {
+	index, err := LoadInstalledIndex()
+	if err != nil {
+		return nil, err
+	}
+	explicit, err := GetInstalledPackages()
+	if err != nil {
+		return nil, err
+	}
+	foreign, err := GetForeignPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	foreignSet := toSet(foreign)
+	listed := make(map[string]bool)
+	diff := &PackageDiff{
+		Missing:         []string{},
+		UnlistedNative:  []string{},
+		UnlistedForeign: []string{},
+		Misplaced:       []MisplacedPackage{},
+	}
+
+	for _, file := range PackageListFiles {
+		list, err := ParsePackageList(fsys, file.File)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range list.Entries() {
+			source := entry.Source
+			if source == "" {
+				source = file.Source
+			}
+			if source != SourcePacman && source != SourceAUR {
+				continue
+			}
+			listed[entry.Name] = true
+			for _, pkg := range index.Covers(entry.Name) {
+				listed[pkg] = true
+			}
+
+			if !index.Has(entry.Name) {
+				if entry.Applies() {
+					diff.Missing = append(diff.Missing, entry.Name)
+				}
+				continue
+			}
+
+			switch {
+			case index.Local.Packages[entry.Name] == nil:
+				// Groups and provided names have no repository of their own
+			case source == SourcePacman && foreignSet[entry.Name]:
+				diff.Misplaced = append(diff.Misplaced, MisplacedPackage{entry.Name, file.File, listFileFor(SourceAUR)})
+			case source == SourceAUR && !foreignSet[entry.Name]:
+				diff.Misplaced = append(diff.Misplaced, MisplacedPackage{entry.Name, file.File, listFileFor(SourcePacman)})
+			}
+		}
+	}
+
+	for _, pkg := range explicit {
+		if listed[pkg] {
+			continue
+		}
+		if foreignSet[pkg] {
+			diff.UnlistedForeign = append(diff.UnlistedForeign, pkg)
+		} else {
+			diff.UnlistedNative = append(diff.UnlistedNative, pkg)
+		}
+	}
+
+	slices.Sort(diff.Missing)
+	slices.Sort(diff.UnlistedNative)
+	slices.Sort(diff.UnlistedForeign)
+	return diff, nil
+}
+
+// toSet converts a slice into a membership map.
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// ShowPackageDiff prints the difference between the package lists and the system,
+// as JSON when asJSON is set.
+func ShowPackageDiff(fsys fs.FS, asJSON bool) error {
+	diff, err := DiffPackages(fsys)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	}
+
+	Print.NewLns(StyleInfoC, "Package lists vs installed system:")
+
+	printGroup := func(title string, pkgs []string) {
+		fmt.Printf("%s %s\n", Bold(title), Dim(fmt.Sprintf("(%d)", len(pkgs))))
+		for _, pkg := range pkgs {
+			fmt.Printf("  %s\n", pkg)
+		}
+		Print.Info()
+	}
+
+	printGroup("Listed but not installed", diff.Missing)
+	printGroup("Installed from repos but not listed", diff.UnlistedNative)
+	printGroup("Installed from AUR/foreign but not listed", diff.UnlistedForeign)
+
+	fmt.Printf("%s %s\n", Bold("In the wrong list"), Dim(fmt.Sprintf("(%d)", len(diff.Misplaced))))
+	for _, m := range diff.Misplaced {
+		fmt.Printf("  %s %s\n", m.Name, Dim(fmt.Sprintf("(%s → %s)", m.ListedIn, m.BelongsIn)))
+	}
+	Print.Info()
+
+	if len(diff.Missing)+len(diff.UnlistedNative)+len(diff.UnlistedForeign)+len(diff.Misplaced) == 0 {
+		Print.Success("Package lists match the installed system!")
+	}
+	return nil
+}
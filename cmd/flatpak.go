@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+)
+
+// FlatpakScope selects a per-user or system-wide Flatpak installation.
+type FlatpakScope string
+
+const (
+	FlatpakUser   FlatpakScope = "user"
+	FlatpakSystem FlatpakScope = "system"
+)
+
+// DefaultFlatpakRemote is the remote apps install from when they don't name one.
+const DefaultFlatpakRemote = "flathub"
+
+// flag returns the flatpak command-line flag for the scope.
+func (s FlatpakScope) flag() string {
+	return "--" + string(s)
+}
+
+// FlatpakRemote is a remote declared in flatpak.txt with a url= attribute.
+type FlatpakRemote struct {
+	Name string
+	URL  string
+}
+
+// splitFlatpakEntries separates remote declarations (entries with url=) from apps, grouping
+// apps by their remote= attribute.
+func splitFlatpakEntries(entries []PackageEntry) ([]FlatpakRemote, map[string][]PackageEntry) {
+	var remotes []FlatpakRemote
+	apps := make(map[string][]PackageEntry)
+	for _, entry := range entries {
+		if url, ok := entry.Attrs["url"]; ok {
+			remotes = append(remotes, FlatpakRemote{Name: entry.Name, URL: url})
+			continue
+		}
+
+		remote := entry.Attrs["remote"]
+		if remote == "" {
+			remote = DefaultFlatpakRemote
+		}
+		apps[remote] = append(apps[remote], entry)
+	}
+	return remotes, apps
+}
+
+// AddFlatpakRemote adds a remote at the given scope unless it already exists.
+func AddFlatpakRemote(remote FlatpakRemote, scope FlatpakScope) error {
+	fmt.Printf("%s Adding remote %s (%s)\n", Dim("→"), remote.Name, scope)
+	err := runPassthrough("flatpak", "remote-add", "--if-not-exists", scope.flag(), remote.Name, remote.URL)
+	if err != nil {
+		return fmt.Errorf("failed to add flatpak remote %s: %w", remote.Name, err)
+	}
+	return nil
+}
+
+// InstallFlatpakPackages adds the remotes in packages/flatpak.txt and installs missing apps at
+// the given scope, limited to the given sections when any are named. Remotes are always added.
+func InstallFlatpakPackages(fsys fs.FS, scope FlatpakScope, sections ...string) error {
+	Print.NewLns(StyleInfoC, fmt.Sprintf("Installing flatpak applications (%s)...", scope))
+
+	if !CheckCommandExists("flatpak") {
+		return fmt.Errorf("flatpak is not installed")
+	}
+
+	all, err := CollectPackages(fsys, SourceFlatpak)
+	if err != nil {
+		return err
+	}
+	remotes, _ := splitFlatpakEntries(all)
+
+	entries, err := CollectPackages(fsys, SourceFlatpak, sections...)
+	if err != nil {
+		return err
+	}
+	_, apps := splitFlatpakEntries(entries)
+
+	for _, remote := range remotes {
+		if err := AddFlatpakRemote(remote, scope); err != nil {
+			return err
+		}
+	}
+
+	for _, remote := range slices.Sorted(maps.Keys(apps)) {
+		fmt.Printf("%s Found %s from %s\n", Dim("→"), pluralize(len(apps[remote]), "app"), remote)
+
+		backend := flatpakBackend{scope: scope, remote: remote}
+		if err := installEntries(backend, apps[remote]); err != nil {
+			return fmt.Errorf("flatpak installation from %s failed: %w", remote, err)
+		}
+	}
+
+	Print.NewLns(StyleSuccess, "Flatpak applications installed successfully!")
+	return nil
+}
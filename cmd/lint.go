@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// LintSeverity classifies a lint issue.
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+)
+
+// LintIssue is a problem found in a package list.
+type LintIssue struct {
+	File     string
+	Line     int
+	Package  string
+	Severity LintSeverity
+	Message  string
+}
+
+// KnownConflicts lists packages that can't be installed together, keyed by each package.
+var KnownConflicts = map[string][]string{
+	"git-delta": {"delta-git"},
+	"delta-git": {"git-delta"},
+	"yay":       {"yay-bin", "yay-git"},
+	"yay-bin":   {"yay", "yay-git"},
+	"yay-git":   {"yay", "yay-bin"},
+	"paru":      {"paru-bin", "paru-git"},
+	"paru-bin":  {"paru", "paru-git"},
+	"paru-git":  {"paru", "paru-bin"},
+}
+
+// DeprecatedPackages maps unmaintained packages to their replacements.
+var DeprecatedPackages = map[string]string{
+	"exa":      "eza",
+	"exa-git":  "eza",
+	"neofetch": "fastfetch",
+}
+
+// ManagedPackages are installed by thunderize itself and shouldn't also be listed.
+var ManagedPackages = map[string]string{
+	"yay":  "installed by the AUR helper bootstrap",
+	"paru": "installed by the AUR helper bootstrap",
+}
+
+// asdfVMClash explains why listing asdf-vm clashes with the version manager 'install dev'
+// uses, or returns "" when that manager is asdf-aur, which installs asdf from this package.
+func asdfVMClash() string {
+	manager, err := GetVersionManager()
+	if err != nil {
+		return fmt.Sprintf("may clash with the dev tool version manager: %v", err)
+	}
+	if manager.Name() == "asdf-aur" {
+		return ""
+	}
+	return fmt.Sprintf("clashes with %s, which 'install dev' uses (select --version-manager asdf-aur to use this package)", manager.Name())
+}
+
+// LintPackages checks the package lists for duplicates, conflicts, deprecated and
+// self-managed packages, repo/AUR misplacement and unsorted sections.
+//
+// Misplacement is checked against the local sync databases; when they can't be read the
+// check is skipped with a warning.
+func LintPackages(fsys fs.FS) ([]LintIssue, error) {
+	var issues []LintIssue
+	var all []PackageEntry
+	lists := make([]*PackageList, 0, len(PackageListFiles))
+
+	for _, file := range PackageListFiles {
+		list, err := ParsePackageList(fsys, file.File)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+
+		for _, entry := range list.Entries() {
+			if entry.Source == "" {
+				entry.Source = file.Source
+			}
+			all = append(all, entry)
+		}
+	}
+
+	byName := make(map[string][]PackageEntry)
+	for _, entry := range all {
+		key := lintKey(entry.Source, entry.Name)
+		byName[key] = append(byName[key], entry)
+	}
+
+	for _, entry := range all {
+		if first := byName[lintKey(entry.Source, entry.Name)][0]; first.File != entry.File || first.Line != entry.Line {
+			issues = append(issues, LintIssue{entry.File, entry.Line, entry.Name, LintError,
+				fmt.Sprintf("duplicate of %s:%d", first.File, first.Line)})
+		}
+
+		for _, other := range KnownConflicts[entry.Name] {
+			if conflicting, ok := byName[lintKey(entry.Source, other)]; ok && entry.Name < other {
+				issues = append(issues, LintIssue{entry.File, entry.Line, entry.Name, LintError,
+					fmt.Sprintf("conflicts with %s (%s:%d)", other, conflicting[0].File, conflicting[0].Line)})
+			}
+		}
+
+		if reason, ok := ManagedPackages[entry.Name]; ok {
+			issues = append(issues, LintIssue{entry.File, entry.Line, entry.Name, LintWarning, reason})
+		}
+		if entry.Name == "asdf-vm" {
+			if reason := asdfVMClash(); reason != "" {
+				issues = append(issues, LintIssue{entry.File, entry.Line, entry.Name, LintWarning, reason})
+			}
+		}
+
+		if replacement, ok := DeprecatedPackages[entry.Name]; ok {
+			issues = append(issues, LintIssue{entry.File, entry.Line, entry.Name, LintWarning,
+				fmt.Sprintf("deprecated, use %s instead", replacement)})
+		}
+	}
+
+	misplaced, err := lintMisplaced(all)
+	if err != nil {
+		issues = append(issues, LintIssue{Severity: LintWarning, Message: fmt.Sprintf("skipped repo/AUR check: %v", err)})
+	}
+	issues = append(issues, misplaced...)
+
+	for _, list := range lists {
+		for _, section := range list.Sections {
+			names := make([]string, len(section.Entries))
+			for i, entry := range section.Entries {
+				names[i] = entry.Name
+			}
+			if !slices.IsSorted(names) {
+				name := section.Name
+				if name == "" {
+					name = "(unsectioned)"
+				}
+				issues = append(issues, LintIssue{list.File, section.Entries[0].Line, "", LintWarning,
+					fmt.Sprintf("section %q is not sorted", name)})
+			}
+		}
+	}
+
+	slices.SortStableFunc(issues, func(a, b LintIssue) int {
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+		return a.Line - b.Line
+	})
+	return issues, nil
+}
+
+// lintKey groups entries by package namespace: pacman and AUR share one, the other backends
+// each have their own.
+func lintKey(source, name string) string {
+	if isArchSource(source) {
+		source = SourcePacman
+	}
+	return source + "/" + name
+}
+
+// lintMisplaced flags AUR entries available in a sync repository and repo entries that are
+// neither a sync package, group nor provided name.
+func lintMisplaced(entries []PackageEntry) ([]LintIssue, error) {
+	db, err := LoadSyncDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []LintIssue
+	for _, entry := range entries {
+		pkg, inSync := db.Packages[entry.Name]
+		switch {
+		case entry.Source == SourceAUR && inSync:
+			issues = append(issues, LintIssue{entry.File, entry.Line, entry.Name, LintError,
+				fmt.Sprintf("available in the %s repository, list it in %s", pkg.Repository, listFileFor(SourcePacman))})
+		case entry.Source == SourcePacman && !db.Has(entry.Name):
+			issues = append(issues, LintIssue{entry.File, entry.Line, entry.Name, LintError,
+				fmt.Sprintf("not in any sync repository, list it in %s", listFileFor(SourceAUR))})
+		}
+	}
+	return issues, nil
+}
+
+// ShowLint lints the package lists, prints the issues and fails when any are errors.
+func ShowLint(fsys fs.FS) error {
+	Print.NewLns(StyleInfoC, "Linting package lists...")
+
+	issues, err := LintPackages(fsys)
+	if err != nil {
+		return err
+	}
+
+	errorCount := 0
+	for _, issue := range issues {
+		location := "packages"
+		if issue.File != "" {
+			location = fmt.Sprintf("%s:%d", issue.File, issue.Line)
+		}
+		subject := issue.Message
+		if issue.Package != "" {
+			subject = Bold(issue.Package) + ": " + issue.Message
+		}
+
+		if issue.Severity == LintError {
+			errorCount++
+			fmt.Printf("  %s %s %s\n", BoldRed("error"), Dim(location), subject)
+		} else {
+			fmt.Printf("  %s %s %s\n", BoldYellow("warning"), Dim(location), subject)
+		}
+	}
+
+	if len(issues) == 0 {
+		Print.Success("No problems found!")
+		return nil
+	}
+
+	Print.Info()
+	warnings := len(issues) - errorCount
+	if errorCount > 0 {
+		return fmt.Errorf("%s, %s", pluralize(errorCount, "error"), pluralize(warnings, "warning"))
+	}
+	Print.Warn(pluralize(warnings, "warning"))
+	return nil
+}
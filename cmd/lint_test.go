@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLintWarnsAboutAsdfVMUnlessAsdfAURIsSelected(t *testing.T) {
+	oldSync := SyncDBDir
+	SyncDBDir = t.TempDir()
+	t.Cleanup(func() {
+		SyncDBDir = oldSync
+		versionManager = ""
+	})
+	fsys := fstest.MapFS{
+		"packages/pacman.txt":  {Data: []byte("")},
+		"packages/aur.txt":     {Data: []byte("asdf-vm\n")},
+		"packages/apt.txt":     {Data: []byte("")},
+		"packages/dnf.txt":     {Data: []byte("")},
+		"packages/flatpak.txt": {Data: []byte("")},
+	}
+
+	for manager, warned := range map[string]bool{"asdf-aur": false, "asdf-git": true, "mise": true} {
+		if err := SetVersionManager(manager); err != nil {
+			t.Fatal(err)
+		}
+		issues, err := LintPackages(fsys)
+		if err != nil {
+			t.Fatalf("LintPackages: %v", err)
+		}
+
+		found := false
+		for _, issue := range issues {
+			if issue.Package == "asdf-vm" {
+				found = true
+			}
+		}
+		if found != warned {
+			t.Errorf("with %s: asdf-vm warned = %v, want %v", manager, found, warned)
+		}
+	}
+}
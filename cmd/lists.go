@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+const (
+	// SourcePacman installs from the official repositories.
+	SourcePacman = "pacman"
+	// SourceAUR installs from the AUR through yay or paru.
+	SourceAUR = "aur"
+	// SourceFlatpak installs Flatpak applications.
+	SourceFlatpak = "flatpak"
+	// SourceApt installs with apt on Debian and Ubuntu.
+	SourceApt = "apt"
+	// SourceDnf installs with dnf on Fedora and RHEL.
+	SourceDnf = "dnf"
+)
+
+// PackageListFiles maps each package list to the source its entries install from by default.
+var PackageListFiles = []struct {
+	File   string
+	Source string
+}{
+	{"packages/pacman.txt", SourcePacman},
+	{"packages/aur.txt", SourceAUR},
+	{"packages/apt.txt", SourceApt},
+	{"packages/dnf.txt", SourceDnf},
+	{"packages/flatpak.txt", SourceFlatpak},
+}
+
+// KnownSources lists the values accepted by the source= attribute.
+var KnownSources = []string{SourcePacman, SourceAUR, SourceApt, SourceDnf, SourceFlatpak}
+
+// PackageEntry is a single package from a list file with its inline attributes.
+//
+// Entries are written as a name followed by optional attributes:
+//
+//	ttf-google-sans-code-nf optional source=aur host=desktop,laptop arch=x86_64
+type PackageEntry struct {
+	Name     string            // Package name
+	Section  string            // Section the entry belongs to ("" before the first header)
+	File     string            // List file the entry came from
+	Line     int               // Line number in the list file
+	Optional bool              // Failures to install are reported but not fatal
+	Hosts    []string          // Hostnames the entry applies to (all when empty)
+	Profiles []string          // Profiles the entry applies to (all when empty)
+	Arch     []string          // Architectures the entry applies to (all when empty)
+	Source   string            // Where to install from; defaults to the list's source
+	Attrs    map[string]string // Every attribute, including tool-specific ones (via=, pkg=, ...)
+}
+
+// PackageSection is a named group of entries introduced by a comment header.
+type PackageSection struct {
+	Name    string
+	Entries []PackageEntry
+}
+
+// PackageList is a parsed package list file.
+type PackageList struct {
+	File     string
+	Sections []*PackageSection
+}
+
+// Entries returns every entry in the list in file order.
+func (l *PackageList) Entries() []PackageEntry {
+	var entries []PackageEntry
+	for _, section := range l.Sections {
+		entries = append(entries, section.Entries...)
+	}
+	return entries
+}
+
+var activeProfiles []string
+
+// SetProfiles selects the profiles used to evaluate profile= conditions.
+func SetProfiles(profiles []string) {
+	activeProfiles = profiles
+}
+
+// GetProfiles returns the active profiles from SetProfiles or $THUNDERIZE_PROFILE (comma-separated).
+func GetProfiles() []string {
+	if len(activeProfiles) > 0 {
+		return activeProfiles
+	}
+	return splitList(os.Getenv("THUNDERIZE_PROFILE"))
+}
+
+// GetArch returns the machine architecture using pacman's names (x86_64, aarch64, ...).
+func GetArch() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x86_64"
+	case "arm64":
+		return "aarch64"
+	case "386":
+		return "i686"
+	default:
+		return runtime.GOARCH
+	}
+}
+
+// Applies reports whether the entry's host, profile and arch conditions match this machine.
+func (e PackageEntry) Applies() bool {
+	if len(e.Hosts) > 0 {
+		host, err := os.Hostname()
+		if err != nil || !slices.Contains(e.Hosts, host) {
+			return false
+		}
+	}
+
+	if len(e.Profiles) > 0 {
+		matched := false
+		for _, profile := range GetProfiles() {
+			if slices.Contains(e.Profiles, profile) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(e.Arch) > 0 && !slices.Contains(e.Arch, GetArch()) {
+		return false
+	}
+	return true
+}
+
+// splitList splits a comma-separated attribute value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// sectionHeader returns the section name for a comment line, or "" for rules like "#####".
+func sectionHeader(line string) string {
+	return strings.TrimSpace(strings.Trim(line, "#"))
+}
+
+// parseEntry parses a list line (without any trailing comment) into an entry.
+func parseEntry(line string) (PackageEntry, error) {
+	fields := strings.Fields(line)
+	entry := PackageEntry{Name: fields[0], Attrs: make(map[string]string)}
+
+	for _, field := range fields[1:] {
+		key, value, _ := strings.Cut(field, "=")
+		entry.Attrs[key] = value
+
+		switch key {
+		case "optional":
+			entry.Optional = true
+		case "host":
+			entry.Hosts = splitList(value)
+		case "profile":
+			entry.Profiles = splitList(value)
+		case "arch":
+			entry.Arch = splitList(value)
+		case "source":
+			if !slices.Contains(KnownSources, value) {
+				return entry, fmt.Errorf("unknown source %q for %s", value, entry.Name)
+			}
+			entry.Source = value
+		}
+	}
+	return entry, nil
+}
+
+// ParsePackageList parses a package list file, keeping sections and inline attributes.
+//
+// Full-line comments name a section ("# Fonts", or a boxed "# Fonts   #" between rules of
+// #s); text after " #" on an entry line is a trailing comment. Plain one-name-per-line
+// lists parse as entries without attributes.
+func ParsePackageList(fsys fs.FS, filename string) (*PackageList, error) {
+	data, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	list := &PackageList{File: filename}
+	current := &PackageSection{}
+	list.Sections = append(list.Sections, current)
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			if name := sectionHeader(line); name != "" {
+				current = &PackageSection{Name: name}
+				list.Sections = append(list.Sections, current)
+			}
+			continue
+		}
+
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+
+		entry, err := parseEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, lineNo, err)
+		}
+		entry.Section = current.Name
+		entry.File = filename
+		entry.Line = lineNo
+		current.Entries = append(current.Entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading package list: %w", err)
+	}
+
+	list.Sections = slices.DeleteFunc(list.Sections, func(s *PackageSection) bool {
+		return s.Name == "" && len(s.Entries) == 0
+	})
+	return list, nil
+}
+
+// CollectPackages returns the entries from every package list that install from source and
+// apply to this machine. Entries without a source= attribute use their list's default source.
+//
+// When sections are given, only entries in those sections (case-insensitive) are returned.
+// A section counts only if it holds entries for source, so naming a section that exists only
+// in another source's list is an error listing the sections source does have.
+func CollectPackages(fsys fs.FS, source string, sections ...string) ([]PackageEntry, error) {
+	var available []string
+	var entries []PackageEntry
+
+	for _, file := range PackageListFiles {
+		list, err := ParsePackageList(fsys, file.File)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range list.Entries() {
+			if entry.Source == "" {
+				entry.Source = file.Source
+			}
+			if entry.Source != source {
+				continue
+			}
+			if entry.Section != "" && !containsFold(available, entry.Section) {
+				available = append(available, entry.Section)
+			}
+			if !entry.Applies() {
+				continue
+			}
+			if len(sections) > 0 && !containsFold(sections, entry.Section) {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	for _, section := range sections {
+		if !containsFold(available, section) {
+			if len(available) == 0 {
+				return nil, fmt.Errorf("unknown %s section: %s (no %s packages are listed)", source, section, source)
+			}
+			return nil, fmt.Errorf("unknown %s section: %s (%s sections: %s)", source, section, source, strings.Join(available, ", "))
+		}
+	}
+	return entries, nil
+}
+
+// containsFold reports whether items contains s, ignoring case.
+func containsFold(items []string, s string) bool {
+	return slices.ContainsFunc(items, func(item string) bool {
+		return strings.EqualFold(item, s)
+	})
+}
+
+// ListSections prints the sections of every package list with their package counts.
+func ListSections(fsys fs.FS) error {
+	Print.NewLns(StyleInfoC, "Package list sections:")
+
+	for _, file := range PackageListFiles {
+		list, err := ParsePackageList(fsys, file.File)
+		if err != nil {
+			return err
+		}
+
+		Print.Info(Bold(file.File))
+		for _, section := range list.Sections {
+			name := section.Name
+			if name == "" {
+				name = "(unsectioned)"
+			}
+
+			applies := 0
+			for _, entry := range section.Entries {
+				if entry.Applies() {
+					applies++
+				}
+			}
+
+			count := pluralize(len(section.Entries), "package")
+			if applies != len(section.Entries) {
+				count = fmt.Sprintf("%s, %d on this machine", count, applies)
+			}
+			fmt.Printf("  %s %s\n", BoldMagenta(fmt.Sprintf("%-24s", name)), Dim(count))
+		}
+		Print.Info()
+	}
+	return nil
+}
+
+// pluralize formats a count with a singular or plural noun ("1 package", "3 packages").
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
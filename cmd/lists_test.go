@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCollectPackagesChecksSectionsAgainstSource(t *testing.T) {
+	fsys := fstest.MapFS{
+		"packages/pacman.txt":  {Data: []byte("# Base\ngit\n\n# Shell\nzsh\naur-only-tool source=aur\n")},
+		"packages/aur.txt":     {Data: []byte("# Applications\nvisual-studio-code-bin\n")},
+		"packages/apt.txt":     {Data: []byte("")},
+		"packages/dnf.txt":     {Data: []byte("")},
+		"packages/flatpak.txt": {Data: []byte("")},
+	}
+
+	entries, err := CollectPackages(fsys, SourcePacman, "shell")
+	if err != nil {
+		t.Fatalf("CollectPackages: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "zsh" {
+		t.Errorf("collected %v, want zsh", entries)
+	}
+
+	_, err = CollectPackages(fsys, SourcePacman, "Applications")
+	if err == nil {
+		t.Fatal("section only in aur.txt was accepted for pacman")
+	}
+	if !strings.Contains(err.Error(), "Base, Shell") {
+		t.Errorf("error %q doesn't list the pacman sections", err)
+	}
+
+	if _, err := CollectPackages(fsys, SourceAUR, "shell"); err != nil {
+		t.Errorf("section holding an aur entry was rejected: %v", err)
+	}
+}
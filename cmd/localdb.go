@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LocalDBDir is where pacman records installed packages.
+var LocalDBDir = "/var/lib/pacman/local"
+
+// markExplicit is set by --mark-explicit to mark listed dependency installs as explicit.
+var markExplicit bool
+
+// SetMarkExplicit sets whether installs mark listed packages installed as dependencies as explicit.
+func SetMarkExplicit(mark bool) {
+	markExplicit = mark
+}
+
+// LoadLocalDB reads the installed packages from LocalDBDir without calling pacman.
+func LoadLocalDB() (*PackageDB, error) {
+	dirs, err := os.ReadDir(LocalDBDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local database: %w", err)
+	}
+
+	db := newPackageDB()
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(LocalDBDir, dir.Name(), "desc")
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		fields, err := parseDesc(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		db.add(packageFromDesc(fields, ""))
+	}
+	return db, nil
+}
+
+// loadLocalDBFromPacman builds the local database from pacman queries, for systems where
+// LocalDBDir isn't readable. Groups and provides aren't available this way.
+func loadLocalDBFromPacman() (*PackageDB, error) {
+	versions, err := GetInstalledVersions()
+	if err != nil {
+		return nil, err
+	}
+	explicit, err := GetInstalledPackages()
+	if err != nil {
+		return nil, err
+	}
+	explicitSet := toSet(explicit)
+
+	db := newPackageDB()
+	for name, version := range versions {
+		db.add(&DBPackage{Name: name, Version: version, Explicit: explicitSet[name]})
+	}
+	return db, nil
+}
+
+// InstalledIndex answers whether list entries are installed, resolving groups and provides.
+type InstalledIndex struct {
+	Local *PackageDB // Installed packages
+	Sync  *PackageDB // Sync databases for group membership, nil when unavailable
+}
+
+// LoadInstalledIndex reads the local database, falling back to pacman queries, and the sync
+// databases when they're available.
+func LoadInstalledIndex() (*InstalledIndex, error) {
+	local, err := LoadLocalDB()
+	if err != nil {
+		local, err = loadLocalDBFromPacman()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sync, err := LoadSyncDB()
+	if err != nil {
+		sync = nil
+	}
+	return &InstalledIndex{Local: local, Sync: sync}, nil
+}
+
+// Has reports whether name is satisfied on this system: an installed package (explicit or as a
+// dependency), a name provided by an installed package, or a group whose members are all installed.
+func (idx *InstalledIndex) Has(name string) bool {
+	if _, ok := idx.Local.Packages[name]; ok {
+		return true
+	}
+	if len(idx.Local.Provides[name]) > 0 {
+		return true
+	}
+
+	members := idx.GroupMembers(name)
+	if len(members) == 0 {
+		return false
+	}
+	for _, member := range members {
+		if _, ok := idx.Local.Packages[member]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// GroupMembers returns the members of group name, from the sync databases when they're
+// available so members that aren't installed are included.
+func (idx *InstalledIndex) GroupMembers(name string) []string {
+	if idx.Sync != nil {
+		return idx.Sync.Groups[name]
+	}
+	return idx.Local.Groups[name]
+}
+
+// Covers returns the installed packages a list entry accounts for: the package itself, the
+// packages providing it and the installed members of a group.
+func (idx *InstalledIndex) Covers(name string) []string {
+	var covered []string
+	if _, ok := idx.Local.Packages[name]; ok {
+		covered = append(covered, name)
+	}
+	covered = append(covered, idx.Local.Provides[name]...)
+	covered = append(covered, idx.Local.Groups[name]...)
+	return covered
+}
+
+// Dependencies returns the names that are installed only as dependencies.
+func (idx *InstalledIndex) Dependencies(names []string) []string {
+	var deps []string
+	for _, name := range names {
+		if pkg, ok := idx.Local.Packages[name]; ok && !pkg.Explicit {
+			deps = append(deps, name)
+		}
+	}
+	return deps
+}
+
+// MarkExplicit marks installed packages as explicitly installed with pacman -D --asexplicit,
+// so they show up in pacman -Qe and survive pacman -Rns of the packages that pulled them in.
+func MarkExplicit(pkgs []string) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+
+	fmt.Printf("%s Marking %s as explicitly installed...\n", Dim("→"), pluralize(len(pkgs), "package"))
+
+	if err := runner.Run(Command("sudo", append([]string{"pacman", "-D", "--asexplicit"}, pkgs...)...)); err != nil {
+		return fmt.Errorf("failed to mark packages as explicit: %w", err)
+	}
+	return nil
+}
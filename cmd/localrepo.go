@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// LocalRepoName is the pacman repository name of a local package repository.
+const LocalRepoName = "thunderize"
+
+// PacmanCacheDir is where pacman keeps downloaded packages.
+var PacmanCacheDir = "/var/cache/pacman/pkg"
+
+// localRepo is the directory set by --local-repo; installs use it instead of the network.
+var localRepo string
+
+// SetLocalRepo makes pacman and AUR installs use the local repository in dir. An empty dir
+// restores network installs.
+func SetLocalRepo(dir string) error {
+	if dir == "" {
+		localRepo = ""
+		return nil
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	if _, err := os.Stat(localRepoDB(abs)); err != nil {
+		return fmt.Errorf("%s is not a local package repository (run 'thunderize packages cache build %s'): %w", dir, dir, err)
+	}
+	localRepo = abs
+	return nil
+}
+
+// localRepoDB returns the repo-add database path in dir.
+func localRepoDB(dir string) string {
+	return filepath.Join(dir, LocalRepoName+".db.tar.zst")
+}
+
+// aurBuildDirs returns the directories AUR helpers leave built packages in.
+func aurBuildDirs() ([]string, error) {
+	homeDir, err := GetHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	cache := filepath.Join(homeDir, ".cache")
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		cache = dir
+	}
+	return []string{
+		filepath.Join(cache, "yay", "*"),
+		filepath.Join(cache, "paru", "clone", "*"),
+	}, nil
+}
+
+// findPackageFile returns the newest built package for name at version in dirs, which may
+// contain glob patterns.
+func findPackageFile(dirs []string, name, version string) string {
+	var found []string
+	for _, dir := range dirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, name+"-"+version+"-*.pkg.tar.*"))
+		for _, match := range matches {
+			if !strings.HasSuffix(match, ".sig") {
+				found = append(found, match)
+			}
+		}
+	}
+	if len(found) == 0 {
+		return ""
+	}
+
+	slices.SortFunc(found, func(a, b string) int {
+		ai, _ := os.Stat(a)
+		bi, _ := os.Stat(b)
+		if ai == nil || bi == nil {
+			return strings.Compare(a, b)
+		}
+		return bi.ModTime().Compare(ai.ModTime())
+	})
+	return found[0]
+}
+
+// resolveClosure returns the installed packages the listed names need: each listed package,
+// group member or provider and, recursively, everything they depend on.
+func resolveClosure(index *InstalledIndex, names []string) []*DBPackage {
+	seen := make(map[string]bool)
+	var closure []*DBPackage
+
+	var visit func(name string)
+	visit = func(name string) {
+		pkg, ok := index.Local.Packages[name]
+		if !ok {
+			providers := index.Local.Provides[name]
+			if len(providers) == 0 {
+				return
+			}
+			pkg = index.Local.Packages[providers[0]]
+		}
+		if seen[pkg.Name] {
+			return
+		}
+		seen[pkg.Name] = true
+		closure = append(closure, pkg)
+
+		for _, dep := range pkg.Depends {
+			visit(dep)
+		}
+	}
+
+	for _, name := range names {
+		for _, covered := range index.Covers(name) {
+			visit(covered)
+		}
+	}
+
+	slices.SortFunc(closure, func(a, b *DBPackage) int { return strings.Compare(a.Name, b.Name) })
+	return closure
+}
+
+// BuildPackageCache collects the built packages for every listed pacman and AUR package and
+// their dependencies from the pacman cache and AUR helper build dirs into dir, and indexes
+// them with repo-add so install --local-repo can reinstall without network access.
+func BuildPackageCache(fsys fs.FS, dir string) error {
+	Print.NewLns(StyleInfoC, fmt.Sprintf("Building local package repository in %s...", dir))
+
+	if !CheckCommandExists("repo-add") {
+		return fmt.Errorf("repo-add not found - install pacman-contrib or pacman")
+	}
+
+	entries, err := listedEntries(fsys)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name
+	}
+
+	index, err := LoadInstalledIndex()
+	if err != nil {
+		return err
+	}
+	buildDirs, err := aurBuildDirs()
+	if err != nil {
+		return err
+	}
+	searchDirs := append([]string{PacmanCacheDir}, buildDirs...)
+
+	if err := ensureDir(dir); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	var files, missing []string
+	for _, pkg := range resolveClosure(index, names) {
+		source := findPackageFile(searchDirs, pkg.Name, pkg.Version)
+		if source == "" {
+			missing = append(missing, pkg.Name+" "+pkg.Version)
+			continue
+		}
+
+		target := filepath.Join(dir, filepath.Base(source))
+		if _, err := os.Stat(target); err != nil {
+			if err := AtomicCopyFile(source, target); err != nil {
+				return err
+			}
+			if _, err := os.Stat(source + ".sig"); err == nil {
+				if err := AtomicCopyFile(source+".sig", target+".sig"); err != nil {
+					return err
+				}
+			}
+		}
+		files = append(files, target)
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("no built packages found in %s or the AUR helper caches", PacmanCacheDir)
+	}
+
+	fmt.Printf("%s Indexing %s...\n", Dim("→"), pluralize(len(files), "package"))
+	args := append([]string{"--new", "--remove", "--quiet", localRepoDB(dir)}, files...)
+	if err := runner.Run(Command("repo-add", args...)); err != nil {
+		return fmt.Errorf("repo-add failed: %w", err)
+	}
+
+	for _, pkg := range missing {
+		fmt.Printf("  %s %s %s\n", BoldYellow("-"), pkg, Dim("(no cached build)"))
+	}
+
+	Print.Beforeln(StyleSuccess, fmt.Sprintf("Cached %s in %s", pluralize(len(files), "package"), dir))
+	if len(missing) > 0 {
+		Print.Warn(fmt.Sprintf("%s missing; reinstall them with network access before rebuilding the cache.",
+			pluralize(len(missing), "package")))
+	}
+	return nil
+}
+
+// localRepoBackend installs from a local repository built by BuildPackageCache, with a
+// pacman config and database directory that list only that repository so nothing is fetched
+// from the network.
+type localRepoBackend struct {
+	dir    string
+	source string // Source recorded for installed packages (pacman or aur)
+}
+
+func (b localRepoBackend) Name() string  { return b.source }
+func (localRepoBackend) Available() bool { return CheckCommandExists("pacman") }
+
+func (localRepoBackend) Missing(pkgs []string) ([]string, error) {
+	return pacmanBackend{}.Missing(pkgs)
+}
+
+// config writes a pacman.conf that only knows the local repository.
+func (b localRepoBackend) config() (string, error) {
+	conf := fmt.Sprintf(`[options]
+Architecture = auto
+
+[%s]
+SigLevel = Optional TrustAll
+Server = file://%s
+`, LocalRepoName, b.dir)
+
+	f, err := os.CreateTemp("", "thunderize-pacman-*.conf")
+	if err != nil {
+		return "", fmt.Errorf("failed to write pacman config: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(conf); err != nil {
+		return "", fmt.Errorf("failed to write pacman config: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// dbPath builds a temporary pacman database directory whose sync databases are only the local
+// repository's and whose local database is the system's, so installs are recorded as usual
+// without refreshing, or leaving anything behind in, the system's sync databases.
+func (b localRepoBackend) dbPath() (string, error) {
+	dir, err := os.MkdirTemp("", "thunderize-pacman-db-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create pacman database directory: %w", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sync"), 0755); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to create pacman database directory: %w", err)
+	}
+
+	links := map[string]string{
+		filepath.Join(dir, "local"):                     LocalDBDir,
+		filepath.Join(dir, "sync", LocalRepoName+".db"): localRepoDB(b.dir),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("failed to link %s: %w", target, err)
+		}
+	}
+	return dir, nil
+}
+
+func (b localRepoBackend) Install(pkgs []string) error {
+	conf, err := b.config()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(conf)
+
+	dbPath, err := b.dbPath()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dbPath)
+
+	args := append([]string{"pacman", "--config", conf, "--dbpath", dbPath, "-S", "--needed", "--noconfirm"}, pkgs...)
+	return runner.Run(Command("sudo", args...))
+}
+
+func (localRepoBackend) Remove(pkgs []string) error {
+	return pacmanBackend{}.Remove(pkgs)
+}
+
+func (b localRepoBackend) Validate(pkgs []string) (map[string]string, error) {
+	db := newPackageDB()
+	if err := readSyncDB(db, localRepoDB(b.dir), LocalRepoName); err != nil {
+		return nil, err
+	}
+	return invalidBy(pkgs, db.Has, "not in the local repository "+b.dir), nil
+}
+
+// localRepoBackendFor returns the local repository backend recording installs under source,
+// and whether --local-repo is set.
+func localRepoBackendFor(source string) (Backend, bool) {
+	if localRepo == "" {
+		return nil, false
+	}
+	return localRepoBackend{dir: localRepo, source: source}, true
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLocalRepoInstallUsesPrivateDBPath(t *testing.T) {
+	oldLocal, oldSync := LocalDBDir, SyncDBDir
+	LocalDBDir, SyncDBDir = t.TempDir(), t.TempDir()
+	t.Cleanup(func() { LocalDBDir, SyncDBDir = oldLocal, oldSync })
+
+	repo := t.TempDir()
+	if err := os.WriteFile(localRepoDB(repo), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The database directory links the system's local database and the repository's own
+	// database, and is removed even when the install fails.
+	var dbPath string
+	rec := &RecordingRunner{Respond: func(c Cmd) ([]byte, error) {
+		i := slices.Index(c.Args, "--dbpath")
+		if i < 0 {
+			t.Fatalf("ran %s without --dbpath", c)
+		}
+		dbPath = c.Args[i+1]
+		links := map[string]string{
+			filepath.Join(dbPath, "local"):                     LocalDBDir,
+			filepath.Join(dbPath, "sync", LocalRepoName+".db"): localRepoDB(repo),
+		}
+		for link, want := range links {
+			if got, err := os.Readlink(link); err != nil || got != want {
+				t.Errorf("%s links to %q (%v), want %s", link, got, err, want)
+			}
+		}
+		return nil, errors.New("exit status 1")
+	}}
+	useRunner(t, rec)
+
+	backend := localRepoBackend{dir: repo, source: SourcePacman}
+	if err := backend.Install([]string{"git"}); err == nil {
+		t.Fatal("Install succeeded after pacman failed")
+	}
+
+	commands := rec.Commands()
+	if len(commands) != 1 {
+		t.Fatalf("ran %v, want only pacman", commands)
+	}
+	if got := commands[0]; got.Name != "sudo" || !slices.Contains(got.Args, "-S") || slices.Contains(got.Args, "-Sy") {
+		t.Errorf("ran %s, want sudo pacman -S without a refresh", got)
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Errorf("%s was left behind", dbPath)
+	}
+	if entries, _ := os.ReadDir(SyncDBDir); len(entries) > 0 {
+		t.Errorf("sync databases were touched: %v", entries)
+	}
+}
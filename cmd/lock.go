@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// LockFile is the package lockfile at the repo root.
+const LockFile = "packages.lock"
+
+// LockedPackage is a package version recorded in the lockfile.
+type LockedPackage struct {
+	Name       string
+	Version    string
+	Repository string // Sync repository (core, extra, ...) or "aur" for foreign packages
+}
+
+// PackageDrift is a difference between the lockfile and the installed system.
+type PackageDrift struct {
+	Name      string
+	Locked    string // Locked version ("" when the package isn't in the lockfile)
+	Installed string // Installed version ("" when the package isn't installed)
+}
+
+// listedEntries returns the pacman and AUR entries that apply to this machine.
+func listedEntries(fsys fs.FS) ([]PackageEntry, error) {
+	var entries []PackageEntry
+	for _, source := range []string{SourcePacman, SourceAUR} {
+		collected, err := CollectPackages(fsys, source)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, collected...)
+	}
+	return entries, nil
+}
+
+// BuildLock collects the installed version and repository of every listed package. Groups
+// are locked as their members and provided names as the packages providing them. Listed
+// packages (and group members) that aren't installed are returned separately.
+func BuildLock(fsys fs.FS) ([]LockedPackage, []string, error) {
+	entries, err := listedEntries(fsys)
+	if err != nil {
+		return nil, nil, err
+	}
+	idx, err := LoadInstalledIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+	if idx.Sync == nil {
+		// Repositories come from the sync databases, so report why they couldn't be read
+		if _, err := LoadSyncDB(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var locked []LockedPackage
+	var missing []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		for _, name := range lockNames(idx, entry.Name) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			pkg, ok := idx.Local.Packages[name]
+			if !ok {
+				missing = append(missing, name)
+				continue
+			}
+
+			// Installed packages missing from the sync databases are foreign (pacman -Qm)
+			repo := SourceAUR
+			if synced, ok := idx.Sync.Packages[name]; ok {
+				repo = synced.Repository
+			}
+			locked = append(locked, LockedPackage{Name: name, Version: pkg.Version, Repository: repo})
+		}
+	}
+
+	slices.SortFunc(locked, func(a, b LockedPackage) int { return strings.Compare(a.Name, b.Name) })
+	slices.Sort(missing)
+	return locked, missing, nil
+}
+
+// lockNames returns the packages a list entry stands for: the package itself when installed,
+// the installed packages providing it, or the members of a group.
+func lockNames(idx *InstalledIndex, name string) []string {
+	if _, ok := idx.Local.Packages[name]; ok {
+		return []string{name}
+	}
+	if providers := idx.Local.Provides[name]; len(providers) > 0 {
+		return providers
+	}
+	if members := idx.GroupMembers(name); len(members) > 0 {
+		return members
+	}
+	return []string{name}
+}
+
+// WriteLock writes locked packages to path, one "name version repository" line each.
+func WriteLock(path string, locked []LockedPackage) error {
+	var b strings.Builder
+	b.WriteString("# Generated by 'thunderize packages lock'. Do not edit.\n")
+	b.WriteString("# name version repository\n")
+	for _, pkg := range locked {
+		fmt.Fprintf(&b, "%s %s %s\n", pkg.Name, pkg.Version, pkg.Repository)
+	}
+	return AtomicWriteFile(path, []byte(b.String()), 0644)
+}
+
+// ReadLock parses a lockfile written by WriteLock.
+func ReadLock(path string) ([]LockedPackage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var locked []LockedPackage
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected 'name version repository'", path, lineNo)
+		}
+		locked = append(locked, LockedPackage{Name: fields[0], Version: fields[1], Repository: fields[2]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading lockfile: %w", err)
+	}
+	return locked, nil
+}
+
+// getLockPath returns the lockfile location in the on-disk repo.
+func getLockPath() (string, error) {
+	if configSource == ConfigSourceEmbedded {
+		return "", fmt.Errorf("the package lockfile lives in the on-disk repo")
+	}
+
+	repoRoot, err := GetRepoRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(repoRoot, LockFile), nil
+}
+
+// LockPackages records the installed version of every listed package in packages.lock.
+func LockPackages(fsys fs.FS) error {
+	Print.NewLns(StyleInfoC, "Locking package versions...")
+
+	path, err := getLockPath()
+	if err != nil {
+		return err
+	}
+
+	locked, missing, err := BuildLock(fsys)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range missing {
+		fmt.Printf("  %s %s\n", pkg, Dim("(not installed, skipped)"))
+	}
+
+	if err := WriteLock(path, locked); err != nil {
+		return err
+	}
+
+	Print.Beforeln(StyleSuccess, fmt.Sprintf("Locked %s in %s", pluralize(len(locked), "package"), path))
+	return nil
+}
+
+// VerifyPackages compares installed versions against packages.lock and reports drift.
+func VerifyPackages(fsys fs.FS) error {
+	Print.NewLns(StyleInfoC, "Verifying package versions against lockfile...")
+
+	path, err := getLockPath()
+	if err != nil {
+		return err
+	}
+
+	locked, err := ReadLock(path)
+	if err != nil {
+		return err
+	}
+	current, _, err := BuildLock(fsys)
+	if err != nil {
+		return err
+	}
+
+	installed := make(map[string]LockedPackage, len(current))
+	for _, pkg := range current {
+		installed[pkg.Name] = pkg
+	}
+
+	var drift []PackageDrift
+	lockedNames := make(map[string]bool, len(locked))
+	for _, pkg := range locked {
+		lockedNames[pkg.Name] = true
+		now, ok := installed[pkg.Name]
+		if !ok || now.Version != pkg.Version {
+			drift = append(drift, PackageDrift{Name: pkg.Name, Locked: pkg.Version, Installed: now.Version})
+		}
+	}
+	for _, pkg := range current {
+		if !lockedNames[pkg.Name] {
+			drift = append(drift, PackageDrift{Name: pkg.Name, Installed: pkg.Version})
+		}
+	}
+
+	if len(drift) == 0 {
+		Print.Success(fmt.Sprintf("All %s match the lockfile!", pluralize(len(locked), "package")))
+		return nil
+	}
+
+	for _, d := range drift {
+		switch {
+		case d.Installed == "":
+			fmt.Printf("  %s %s %s\n", BoldRed("✗"), d.Name, Dim(fmt.Sprintf("(locked %s, not installed)", d.Locked)))
+		case d.Locked == "":
+			fmt.Printf("  %s %s %s\n", BoldYellow("+"), d.Name, Dim(fmt.Sprintf("(installed %s, not locked)", d.Installed)))
+		default:
+			fmt.Printf("  %s %s %s → %s\n", BoldYellow("~"), d.Name, d.Locked, d.Installed)
+		}
+	}
+	Print.Info()
+	return fmt.Errorf("%s drifted from %s", pluralize(len(drift), "package"), LockFile)
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// writeSyncDB writes an uncompressed sync database for repo to SyncDBDir, with each package
+// in the groups given for it.
+func writeSyncDB(t *testing.T, repo string, pkgs map[string][]string) {
+	t.Helper()
+	f, err := os.Create(filepath.Join(SyncDBDir, repo+".db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	for name, groups := range pkgs {
+		desc := "%NAME%\n" + name + "\n\n%VERSION%\n1.0-1\n\n"
+		if len(groups) > 0 {
+			desc += "%GROUPS%\n"
+			for _, group := range groups {
+				desc += group + "\n"
+			}
+			desc += "\n"
+		}
+		hdr := &tar.Header{Name: name + "-1.0-1/desc", Mode: 0644, Size: int64(len(desc))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(desc)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildLockExpandsGroups(t *testing.T) {
+	useLocalDB(t, []string{"git", "gcc", "yay"}, []string{"make"})
+	writeSyncDB(t, "core", map[string][]string{
+		"git":   nil,
+		"gcc":   {"base-devel"},
+		"make":  {"base-devel"},
+		"patch": {"base-devel"},
+	})
+	fsys := fstest.MapFS{
+		"packages/pacman.txt":  {Data: []byte("git\nbase-devel\n")},
+		"packages/aur.txt":     {Data: []byte("yay\n")},
+		"packages/apt.txt":     {Data: []byte("")},
+		"packages/dnf.txt":     {Data: []byte("")},
+		"packages/flatpak.txt": {Data: []byte("")},
+	}
+
+	locked, missing, err := BuildLock(fsys)
+	if err != nil {
+		t.Fatalf("BuildLock: %v", err)
+	}
+
+	want := []LockedPackage{
+		{Name: "gcc", Version: "1.0-1", Repository: "core"},
+		{Name: "git", Version: "1.0-1", Repository: "core"},
+		{Name: "make", Version: "1.0-1", Repository: "core"},
+		{Name: "yay", Version: "1.0-1", Repository: SourceAUR},
+	}
+	if !slices.Equal(locked, want) {
+		t.Errorf("locked %v, want %v", locked, want)
+	}
+	if !slices.Equal(missing, []string{"patch"}) {
+		t.Errorf("missing %v, want the uninstalled group member patch", missing)
+	}
+}
@@ -1,90 +1,59 @@
 package cmd
 
 import (
-	"bufio"
-	"embed"
 	"fmt"
-	"os"
-	"os/exec"
+	"io/fs"
+	"slices"
 	"strings"
 )
 
-// ReadPackageList reads a package list file and returns non-empty, non-comment lines.
-func ReadPackageList(fs embed.FS, filename string) ([]string, error) {
-	data, err := fs.ReadFile(filename)
+// ReadPackageList reads a package list file and returns the names of entries that apply to this machine.
+func ReadPackageList(fsys fs.FS, filename string) ([]string, error) {
+	list, err := ParsePackageList(fsys, filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+		return nil, err
 	}
 
 	var packages []string
-	scanner := bufio.NewScanner(strings.NewReader(string(data)))
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	for _, entry := range list.Entries() {
+		if entry.Applies() {
+			packages = append(packages, entry.Name)
 		}
-		packages = append(packages, line)
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading package list: %w", err)
-	}
-
 	return packages, nil
 }
 
-// FilterInstalledPackages removes already-installed packages from the list.
-func FilterInstalledPackages(packages []string) ([]string, error) {
-	installed, err := GetInstalledPackages()
-	if err != nil {
-		return nil, err
-	}
-
-	installedMap := make(map[string]bool)
-	for _, pkg := range installed {
-		installedMap[pkg] = true
-	}
-
+// FilterInstalledPackages removes already-installed packages from the list. Packages installed
+// as dependencies, names provided by installed packages and fully installed groups count as
+// installed.
+func FilterInstalledPackages(index *InstalledIndex, packages []string) []string {
 	var toInstall []string
 	for _, pkg := range packages {
-		if !installedMap[pkg] {
+		if !index.Has(pkg) {
 			toInstall = append(toInstall, pkg)
 		}
 	}
-
-	return toInstall, nil
+	return toInstall
 }
 
-// InstallPacmanPackages installs packages using pacman.
-func InstallPacmanPackages(fs embed.FS) error {
+// InstallPacmanPackages installs packages using pacman, limited to the given sections when any are named.
+func InstallPacmanPackages(fsys fs.FS, sections ...string) error {
 	Print.NewLns(StyleInfoC, "Installing pacman packages...")
 
-	packages, err := ReadPackageList(fs, "packages/pacman.txt")
+	entries, err := CollectPackages(fsys, SourcePacman, sections...)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("%s Found %d packages in list\n", Dim("→"), len(packages))
+	fmt.Printf("%s Found %d packages in list\n", Dim("→"), len(entries))
 
-	toInstall, err := FilterInstalledPackages(packages)
-	if err != nil {
-		return err
+	var backend Backend = pacmanBackend{}
+	if local, ok := localRepoBackendFor(SourcePacman); ok {
+		fmt.Printf("%s Installing from local repository %s\n", Dim("→"), localRepo)
+		backend = local
 	}
 
-	if len(toInstall) == 0 {
-		Print.Success("All pacman packages already installed!")
-		return nil
-	}
-
-	fmt.Printf("%s Installing %d new packages...\n\n", Dim("→"), len(toInstall))
-
-	args := append([]string{"-S", "--needed", "--noconfirm"}, toInstall...)
-	cmd := exec.Command("sudo", append([]string{"pacman"}, args...)...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
+	if err := installEntries(backend, entries); err != nil {
 		return fmt.Errorf("pacman installation failed: %w", err)
 	}
 
@@ -92,85 +61,63 @@ func InstallPacmanPackages(fs embed.FS) error {
 	return nil
 }
 
-// InstallAURHelper installs yay or paru if not already installed.
-func InstallAURHelper() error {
-	if CheckCommandExists("yay") || CheckCommandExists("paru") {
-		return nil
+// InstallSystemPackages installs the list for the distribution's package manager, detected from
+// /etc/os-release: pacman.txt on Arch, apt.txt on Debian and Ubuntu, dnf.txt on Fedora.
+func InstallSystemPackages(fsys fs.FS, sections ...string) error {
+	backend, err := GetSystemBackend()
+	if err != nil {
+		return err
 	}
-
-	Print.NewLns(StyleInfoC, "Installing AUR helper (yay)...")
-
-	prereqs := []string{"base-devel", "git"}
-	cmd := exec.Command("sudo", "pacman", "-S", "--needed", "--noconfirm", prereqs[0], prereqs[1])
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to install prerequisites: %w", err)
+	if backend.Name() == SourcePacman {
+		return InstallPacmanPackages(fsys, sections...)
 	}
 
-	tmpDir := "/tmp/yay-install"
-	if err := os.RemoveAll(tmpDir); err != nil {
-		return fmt.Errorf("failed to clean temp directory: %w", err)
-	}
+	Print.NewLns(StyleInfoC, fmt.Sprintf("Installing %s packages...", backend.Name()))
 
-	cloneCmd := exec.Command("git", "clone", "https://aur.archlinux.org/yay.git", tmpDir)
-	cloneCmd.Stdout = os.Stdout
-	cloneCmd.Stderr = os.Stderr
-	if err := cloneCmd.Run(); err != nil {
-		return fmt.Errorf("failed to clone yay: %w", err)
+	entries, err := CollectPackages(fsys, backend.Name(), sections...)
+	if err != nil {
+		return err
 	}
 
-	buildCmd := exec.Command("makepkg", "-si", "--noconfirm")
-	buildCmd.Dir = tmpDir
-	buildCmd.Stdout = os.Stdout
-	buildCmd.Stderr = os.Stderr
-	if err := buildCmd.Run(); err != nil {
-		return fmt.Errorf("failed to build yay: %w", err)
+	fmt.Printf("%s Found %d packages in list\n", Dim("→"), len(entries))
+
+	if err := installEntries(backend, entries); err != nil {
+		return fmt.Errorf("%s installation failed: %w", backend.Name(), err)
 	}
 
-	Print.NewLns(StyleSuccess, "AUR helper installed successfully!")
+	Print.NewLns(StyleSuccess, fmt.Sprintf("%s packages installed successfully!", backend.Name()))
 	return nil
 }
 
-// InstallAURPackages installs packages from AUR using yay or paru.
-func InstallAURPackages(fs embed.FS) error {
+// InstallAURPackages installs packages from AUR using yay or paru, limited to the given sections when any are named.
+func InstallAURPackages(fsys fs.FS, sections ...string) error {
 	Print.NewLns(StyleInfoC, "Installing AUR packages...")
-	if err := InstallAURHelper(); err != nil {
-		return err
-	}
 
-	aurHelper, err := GetPackageManager()
-	if err != nil {
-		return err
-	}
+	backend, ok := localRepoBackendFor(SourceAUR)
+	if ok {
+		fmt.Printf("%s Installing from local repository %s\n", Dim("→"), localRepo)
+	} else {
+		if err := InstallAURHelper(); err != nil {
+			return err
+		}
 
-	fmt.Printf("%s Using %s as AUR helper\n", Dim("→"), aurHelper)
+		helper, err := bootstrappedAURHelper()
+		if err != nil {
+			return err
+		}
 
-	packages, err := ReadPackageList(fs, "packages/aur.txt")
-	if err != nil {
-		return err
+		fmt.Printf("%s Using %s as AUR helper\n", Dim("→"), helper)
+		backend = aurBackend{helper: helper}
 	}
 
-	fmt.Printf("%s Found %d packages in list\n", Dim("→"), len(packages))
-
-	toInstall, err := FilterInstalledPackages(packages)
+	entries, err := CollectPackages(fsys, SourceAUR, sections...)
 	if err != nil {
 		return err
 	}
 
-	if len(toInstall) == 0 {
-		Print.Success("All AUR packages already installed!")
-		return nil
-	}
-
-	fmt.Printf("%s Installing %d new packages...\n\n", Dim("→"), len(toInstall))
-
-	args := append([]string{"-S", "--needed", "--noconfirm"}, toInstall...)
-	cmd := exec.Command(aurHelper, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	fmt.Printf("%s Found %d packages in list\n", Dim("→"), len(entries))
 
-	if err := cmd.Run(); err != nil {
+	if err := installEntries(backend, entries); err != nil {
 		return fmt.Errorf("AUR installation failed: %w", err)
 	}
 
@@ -178,128 +125,113 @@ func InstallAURPackages(fs embed.FS) error {
 	return nil
 }
 
-// InstallAsdf installs asdf-vm if not already installed.
-func InstallAsdf() error {
-	homeDir, err := GetHomeDir()
+// InstallDevTools installs the tool versions in config/tool-versions with the version
+// manager selected by SetVersionManager, installing the manager first when needed. Each
+// tool version installs independently (see InstallToolVersions) and a summary follows.
+// Plugins are installed from the sources listed in packages/plugins.txt.
+func InstallDevTools(fsys fs.FS) error {
+	manager, err := GetVersionManager()
 	if err != nil {
 		return err
 	}
-
-	asdfDir := homeDir + "/.asdf"
-
-	if _, err := os.Stat(asdfDir); err == nil {
-		Print.Success("asdf already installed!")
-		return nil
-	}
-
-	Print.NewLns(StyleInfoC, "Installing asdf-vm...")
-
-	cmd := exec.Command("git", "clone", "https://github.com/asdf-vm/asdf.git", asdfDir, "--branch", "v0.14.0")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to clone asdf: %w", err)
-	}
-
-	Print.NewLns(StyleSuccess, "asdf-vm installed successfully!")
-	Print.Warn("Note: Source your shell config or restart your shell to use asdf")
-	return nil
-}
-
-// InstallAsdfPlugin installs an asdf plugin if not already installed.
-func InstallAsdfPlugin(plugin string) error {
-	cmd := exec.Command("asdf", "plugin", "list")
-	output, err := cmd.Output()
+	sources, err := ReadPluginSources(fsys)
 	if err != nil {
-		return fmt.Errorf("failed to list asdf plugins: %w", err)
-	}
-
-	installedPlugins := strings.SplitSeq(strings.TrimSpace(string(output)), "\n")
-	for installed := range installedPlugins {
-		if installed == plugin {
-			return nil
-		}
-	}
-
-	fmt.Printf("%s Installing asdf plugin: %s\n", Dim("→"), plugin)
-	installCmd := exec.Command("asdf", "plugin", "add", plugin)
-	installCmd.Stdout = os.Stdout
-	installCmd.Stderr = os.Stderr
-	if err := installCmd.Run(); err != nil {
-		return fmt.Errorf("failed to install asdf plugin %s: %w", plugin, err)
+		return err
 	}
 
-	return nil
-}
+	Print.NewLns(StyleInfoC, fmt.Sprintf("Installing development tools via %s...", manager.Name()))
 
-// InstallDevTools installs development tools via asdf.
-func InstallDevTools() error {
-	Print.NewLns(StyleInfoC, "Installing development tools via asdf...")
-
-	if err := InstallAsdf(); err != nil {
+	if err := manager.Setup(); err != nil {
 		return err
 	}
 
-	repoRoot, err := GetRepoRoot()
+	path, err := ToolVersionsPath()
 	if err != nil {
 		return err
 	}
-
-	toolVersionsPath := repoRoot + "/config/tool-versions"
-	data, err := os.ReadFile(toolVersionsPath)
+	tools, err := ReadToolVersions(path)
 	if err != nil {
-		return fmt.Errorf("failed to read tool-versions: %w", err)
+		return err
 	}
 
-	var tools []string
-	scanner := bufio.NewScanner(strings.NewReader(string(data)))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
+	// Plugins are added one at a time; asdf doesn't support concurrent plugin installs.
+	Print.Dimmed("Installing plugins...")
+	var installable []ToolVersion
+	var results []ToolResult
+	for _, tool := range tools {
+		if err := manager.EnsurePlugin(pluginSource(sources, tool.Name)); err != nil {
+			reason := "plugin: " + lastLine(nil, err)
+			fmt.Printf("  %s %s %s\n", BoldRed("✗"), tool.Name, Dim(reason))
+			results = append(results, ToolResult{Tool: tool, Version: strings.Join(tool.Versions, " "), Status: StatusFailed, Reason: reason})
 			continue
 		}
-		parts := strings.Fields(line)
-		if len(parts) >= 1 {
-			tools = append(tools, parts[0])
-		}
+		installable = append(installable, tool)
 	}
 
-	Print.Dimmed("Installing asdf plugins...")
-	for _, tool := range tools {
-		if err := InstallAsdfPlugin(tool); err != nil {
-			Print.Warn(fmt.Sprintf("Warning: Failed to install plugin %s: %v", tool, err))
-		}
+	logDir, err := toolLogDir()
+	if err != nil {
+		return err
 	}
 
 	Print.Info()
-	fmt.Println(Dim("Installing tool versions..."))
-	fmt.Printf("%s This may take a while...\n\n", Dim("→"))
-
-	installCmd := exec.Command("asdf", "install")
-	installCmd.Dir = repoRoot + "/config"
-	installCmd.Stdout = os.Stdout
-	installCmd.Stderr = os.Stderr
-	if err := installCmd.Run(); err != nil {
-		return fmt.Errorf("failed to install asdf tools: %w", err)
+	fmt.Println(Dim(fmt.Sprintf("Installing tool versions (%d at a time)...", toolJobs)))
+	fmt.Printf("%s This may take a while; logs are in %s\n\n", Dim("→"), logDir)
+
+	results = append(results, InstallToolVersions(manager, installable, logDir)...)
+	order := make(map[string]int, len(tools))
+	for i, tool := range tools {
+		order[tool.Name] = i
+	}
+	slices.SortStableFunc(results, func(a, b ToolResult) int {
+		return order[a.Tool.Name] - order[b.Tool.Name]
+	})
+	if err := printToolSummary(results); err != nil {
+		return err
 	}
 
-	Print.NewLns(StyleSuccess, "Development tools installed successfully!")
+	Print.Beforeln(StyleSuccess, "Development tools installed successfully!")
 	return nil
 }
 
-// InstallAllPackages installs all packages (pacman, AUR, and dev tools).
-func InstallAllPackages(fs embed.FS) error {
+// InstallAllPackages installs all packages (system packages, AUR on Arch, flatpaks when flatpak
+// is installed, and dev tools).
+func InstallAllPackages(fsys fs.FS) error {
 	Print.NewLns(StyleInfoC, "Installing all packages...")
-	if err := InstallPacmanPackages(fs); err != nil {
+
+	backend, err := GetSystemBackend()
+	if err != nil {
 		return err
 	}
+	if err := RunStep(backend.Name()+" packages", func() error { return InstallSystemPackages(fsys) }); err != nil {
+		return err
+	}
+	if backend.Name() == SourcePacman {
+		Print.Info()
+		if err := RunStep("aur packages", func() error { return InstallAURPackages(fsys) }); err != nil {
+			return err
+		}
+	}
+
+	if localRepo != "" {
+		Print.Warn("Skipping flatpaks and dev tools: they need network access.")
+		Print.NewLns(StyleSuccess, "All packages installed successfully!")
+		return nil
+	}
+
+	if CheckCommandExists("flatpak") {
+		Print.Info()
+		if err := RunStep("flatpak apps", func() error { return InstallFlatpakPackages(fsys, FlatpakUser) }); err != nil {
+			return err
+		}
+	}
+
 	Print.Info()
-	if err := InstallAURPackages(fs); err != nil {
+	if err := RunStep("dev tools", func() error { return InstallDevTools(fsys) }); err != nil {
 		return err
 	}
 
 	Print.Info()
-	if err := InstallDevTools(); err != nil {
+	if err := RunStep("dev packages", func() error { return InstallDevPackages(fsys) }); err != nil {
 		return err
 	}
 	Print.NewLns(StyleSuccess, "All packages installed successfully!")
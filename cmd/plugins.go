@@ -0,0 +1,374 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// PluginSource is where the asdf plugin for a tool comes from, as listed in packages/plugins.txt.
+type PluginSource struct {
+	Name string
+	URL  string // Git remote ("" to use the short-name registry)
+	Ref  string // Branch, tag or commit to check out ("" for the default branch)
+}
+
+// Custom reports whether the plugin has a git source of its own instead of the registry.
+func (p PluginSource) Custom() bool {
+	return p.URL != ""
+}
+
+// Pinned reports whether the source fixes a ref. Without one the plugin follows its default
+// branch.
+func (p PluginSource) Pinned() bool {
+	return p.Custom() && p.Ref != ""
+}
+
+// String formats the source as url@ref.
+func (p PluginSource) String() string {
+	if !p.Custom() {
+		return "registry"
+	}
+	if p.Ref != "" {
+		return p.URL + "@" + p.Ref
+	}
+	return p.URL
+}
+
+// ReadPluginSources parses packages/plugins.txt into plugin sources by tool name.
+func ReadPluginSources(fsys fs.FS) (map[string]PluginSource, error) {
+	list, err := ParsePackageList(fsys, "packages/plugins.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string]PluginSource)
+	for _, entry := range list.Entries() {
+		if !entry.Applies() {
+			continue
+		}
+		source := PluginSource{Name: entry.Name, URL: entry.Attrs["url"], Ref: entry.Attrs["ref"]}
+		if source.URL == "" {
+			return nil, fmt.Errorf("%s:%d: plugin %s has no git URL (url=)", entry.File, entry.Line, entry.Name)
+		}
+		if _, ok := sources[source.Name]; ok {
+			return nil, fmt.Errorf("%s:%d: plugin %s is listed twice", entry.File, entry.Line, entry.Name)
+		}
+		sources[source.Name] = source
+	}
+	return sources, nil
+}
+
+// pluginSource returns the listed source for tool, or the registry when it isn't listed.
+func pluginSource(sources map[string]PluginSource, tool string) PluginSource {
+	if source, ok := sources[tool]; ok {
+		return source
+	}
+	return PluginSource{Name: tool}
+}
+
+// PluginCheckout is the git checkout of an installed plugin.
+type PluginCheckout struct {
+	URL  string // origin remote
+	Head string // Checked out commit
+}
+
+// readPluginCheckout reads the origin and HEAD of the plugin checkout in dir.
+func readPluginCheckout(dir string) (PluginCheckout, error) {
+	var checkout PluginCheckout
+	if _, err := os.Stat(dir); err != nil {
+		return checkout, fmt.Errorf("plugin not installed: %w", err)
+	}
+
+	url, err := runner.Output(Command("git", "-C", dir, "remote", "get-url", "origin"))
+	if err != nil {
+		return checkout, fmt.Errorf("failed to read plugin remote: %w", err)
+	}
+	head, err := runner.Output(Command("git", "-C", dir, "rev-parse", "HEAD"))
+	if err != nil {
+		return checkout, fmt.Errorf("failed to read plugin commit: %w", err)
+	}
+	checkout.URL, checkout.Head = strings.TrimSpace(string(url)), strings.TrimSpace(string(head))
+	return checkout, nil
+}
+
+// resolvePluginRef returns the commit ref names in the plugin checkout in dir. Branches are
+// looked up on origin too, since a plugin clone only has its default branch locally.
+func resolvePluginRef(dir, ref string) (string, error) {
+	for _, candidate := range []string{ref, "origin/" + ref} {
+		out, err := runner.Output(Command("git", "-C", dir, "rev-parse", "--verify", "--quiet", candidate+"^{commit}"))
+		if err == nil {
+			return strings.TrimSpace(string(out)), nil
+		}
+	}
+	return "", fmt.Errorf("ref %s not found in plugin checkout", ref)
+}
+
+// sameGitURL compares git remotes, ignoring a trailing slash or .git.
+func sameGitURL(a, b string) bool {
+	normalize := func(url string) string {
+		return strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	}
+	return normalize(a) == normalize(b)
+}
+
+// checkPluginCheckout returns why the plugin checkout in dir doesn't match source, or "" when
+// it does. Registry plugins match whatever is installed, and a source without a ref matches any
+// commit from its URL.
+func checkPluginCheckout(dir string, source PluginSource) (PluginCheckout, string, error) {
+	checkout, err := readPluginCheckout(dir)
+	if err != nil {
+		return checkout, "", err
+	}
+	if !source.Custom() {
+		return checkout, "", nil
+	}
+
+	if !sameGitURL(checkout.URL, source.URL) {
+		return checkout, "installed from another URL", nil
+	}
+	if source.Ref != "" {
+		commit, err := resolvePluginRef(dir, source.Ref)
+		if err != nil {
+			return checkout, err.Error(), nil
+		}
+		if commit != checkout.Head {
+			return checkout, fmt.Sprintf("not at %s (%s)", source.Ref, shortCommit(commit)), nil
+		}
+	}
+	return checkout, "", nil
+}
+
+// verifyInstalledPlugin checks a freshly installed plugin against its source, and warns when
+// the source has no ref, since the plugin then runs whatever its default branch holds. Dry
+// runs don't install anything, so there's nothing to check.
+func verifyInstalledPlugin(dir string, source PluginSource) error {
+	if IsDryRun() || !source.Custom() {
+		return nil
+	}
+	checkout, mismatch, err := checkPluginCheckout(dir, source)
+	if err != nil {
+		return fmt.Errorf("failed to verify plugin %s: %w", source.Name, err)
+	}
+	if mismatch != "" {
+		return fmt.Errorf("plugin %s does not match packages/plugins.txt: %s", source.Name, mismatch)
+	}
+	fmt.Printf("  %s %s at %s\n", Dim("→"), source.Name, shortCommit(checkout.Head))
+	if !source.Pinned() {
+		Print.Warn(fmt.Sprintf("Warning: plugin %s has no ref in packages/plugins.txt - pin it with 'thunderize tools plugins pin'",
+			source.Name))
+	}
+	return nil
+}
+
+// shortCommit abbreviates a commit hash for display.
+func shortCommit(commit string) string {
+	return commit[:min(len(commit), 12)]
+}
+
+// PluginStatus is the outcome of checking an installed plugin against packages/plugins.txt.
+type PluginStatus struct {
+	Source   PluginSource
+	Checkout PluginCheckout
+	Problem  string // Why the plugin doesn't match its source ("" when it does)
+	Missing  bool   // The plugin isn't installed
+	Unused   bool   // The manager installs the tool without a plugin
+}
+
+// VerifyPlugins checks the plugin of every tool in config/tool-versions, and every listed
+// plugin, against the sources in packages/plugins.txt.
+func VerifyPlugins(fsys fs.FS) ([]PluginStatus, error) {
+	manager, err := GetVersionManager()
+	if err != nil {
+		return nil, err
+	}
+	sources, err := ReadPluginSources(fsys)
+	if err != nil {
+		return nil, err
+	}
+	path, err := ToolVersionsPath()
+	if err != nil {
+		return nil, err
+	}
+	tools, err := ReadToolVersions(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	var extra []string
+	for name := range sources {
+		if !slices.Contains(names, name) {
+			extra = append(extra, name)
+		}
+	}
+	slices.Sort(extra)
+	names = append(names, extra...)
+
+	var statuses []PluginStatus
+	for _, name := range names {
+		source := pluginSource(sources, name)
+		dir := manager.PluginDir(source)
+		if dir == "" {
+			// The manager resolves this tool without a plugin (mise's core backends and registry).
+			statuses = append(statuses, PluginStatus{Source: source, Unused: true})
+			continue
+		}
+
+		status := PluginStatus{Source: source}
+		if _, err := os.Stat(dir); err != nil {
+			status.Missing = true
+		} else if checkout, problem, err := checkPluginCheckout(dir, source); err != nil {
+			status.Problem = lastLine(nil, err)
+		} else {
+			status.Checkout, status.Problem = checkout, problem
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// ShowPluginVerify prints each plugin's listed and installed source, returning an error when
+// any installed plugin doesn't match it.
+func ShowPluginVerify(fsys fs.FS) error {
+	Print.NewLns(StyleInfoC, "Verifying plugins...")
+
+	statuses, err := VerifyPlugins(fsys)
+	if err != nil {
+		return err
+	}
+
+	t := newTable("Plugin", "Source", "Installed", "Status")
+	mismatched, missing, unpinned := 0, 0, 0
+	for _, status := range statuses {
+		installed := ""
+		if status.Checkout.URL != "" {
+			installed = status.Checkout.URL + "@" + shortCommit(status.Checkout.Head)
+		}
+
+		var state string
+		switch {
+		case status.Missing:
+			missing++
+			state = BoldYellow("not installed")
+		case status.Unused:
+			state = Dim("no plugin needed")
+		case status.Problem != "":
+			mismatched++
+			state = BoldRed(status.Problem)
+		case !status.Source.Custom():
+			state = Dim("registry")
+		case !status.Source.Pinned():
+			unpinned++
+			state = BoldYellow("unpinned")
+		default:
+			state = BoldGreen("ok")
+		}
+		t.Row(status.Source.Name, status.Source.String(), installed, state)
+	}
+	fmt.Println(t)
+
+	if missing > 0 {
+		Print.Beforeln(StyleWarn, fmt.Sprintf("%s not installed - install with 'thunderize install dev'", pluralize(missing, "plugin")))
+	}
+	if unpinned > 0 {
+		Print.Beforeln(StyleWarn, fmt.Sprintf("%s follow their default branch - pin them with 'thunderize tools plugins pin'",
+			pluralize(unpinned, "plugin")))
+	}
+	if mismatched > 0 {
+		return fmt.Errorf("%s not matching packages/plugins.txt - remove and reinstall them with 'thunderize install dev'",
+			pluralize(mismatched, "plugin"))
+	}
+	Print.Beforeln(StyleSuccess, "Installed plugins match packages/plugins.txt!")
+	return nil
+}
+
+// PinPlugins pins every listed plugin without a ref at the commit it's installed at, by adding
+// ref=<commit> to its line in the repo's packages/plugins.txt. Plugins that aren't installed,
+// aren't used by the version manager or don't match their source are left alone.
+func PinPlugins(fsys fs.FS) error {
+	Print.NewLns(StyleInfoC, "Pinning plugins...")
+
+	statuses, err := VerifyPlugins(fsys)
+	if err != nil {
+		return err
+	}
+	repoRoot, err := GetRepoRoot()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(repoRoot, "packages", "plugins.txt")
+
+	pinned, skipped := 0, 0
+	for _, status := range statuses {
+		source := status.Source
+		if !source.Custom() || source.Pinned() {
+			continue
+		}
+
+		var reason string
+		switch {
+		case status.Missing:
+			reason = "not installed"
+		case status.Unused:
+			reason = "no plugin needed"
+		case status.Problem != "":
+			reason = status.Problem
+		}
+		if reason != "" {
+			skipped++
+			fmt.Printf("  %s %s %s\n", BoldYellow("-"), source.Name, Dim("("+reason+")"))
+			continue
+		}
+
+		if err := setPluginRef(path, source.Name, status.Checkout.Head); err != nil {
+			return err
+		}
+		pinned++
+		fmt.Printf("  %s %s at %s\n", BoldGreen("✓"), source.Name, shortCommit(status.Checkout.Head))
+	}
+
+	Print.Beforeln(StyleSuccess, fmt.Sprintf("Pinned %s in %s", pluralize(pinned, "plugin"), path))
+	if skipped > 0 {
+		Print.Warn(fmt.Sprintf("%s left unpinned; install them with 'thunderize install dev' and pin again.",
+			pluralize(skipped, "plugin")))
+	}
+	return nil
+}
+
+// setPluginRef sets ref= on the plugin's line in the plugins.txt at path, replacing any ref it
+// had and keeping a trailing comment.
+func setPluginRef(path, name, ref string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		entry, comment := line, ""
+		if j := strings.Index(line, " #"); j >= 0 {
+			entry, comment = line[:j], line[j:]
+		}
+		fields := strings.Fields(entry)
+		if len(fields) == 0 || fields[0] != name {
+			continue
+		}
+
+		fields = slices.DeleteFunc(fields, func(field string) bool { return strings.HasPrefix(field, "ref=") })
+		lines[i] = strings.Join(append(fields, "ref="+ref), " ") + comment
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		return AtomicWriteFile(path, []byte(strings.Join(lines, "\n")), info.Mode().Perm())
+	}
+	return fmt.Errorf("%s is not in %s", name, path)
+}
@@ -61,6 +61,7 @@ func (p printer) Warn(a ...any) {
 	}
 	msg := fmt.Sprint(a...)
 	fmt.Println(BoldYellow(msg))
+	recordWarning(msg)
 }
 
 // Err prints error messages in bold red.
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const installedStateFile = "installed.json"
+
+// ProtectedListFile is an optional package list of extra packages prune must never remove.
+const ProtectedListFile = "packages/protected.txt"
+
+// ProtectedPackages are never removed by prune, whatever the lists or the install record say.
+var ProtectedPackages = []string{
+	"base", "base-devel", "linux", "linux-lts", "linux-zen", "linux-firmware",
+	"filesystem", "glibc", "systemd", "pacman", "sudo", "grub", "efibootmgr",
+	"networkmanager", "git", "yay", "paru",
+}
+
+// InstalledRecord is a package thunderize installed.
+type InstalledRecord struct {
+	Source      string    `json:"source"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// InstalledState tracks the packages thunderize installed, keyed by name.
+type InstalledState struct {
+	Packages map[string]InstalledRecord `json:"packages"`
+}
+
+// LoadInstalledState reads the record of packages installed by thunderize.
+func LoadInstalledState() (*InstalledState, error) {
+	state := &InstalledState{}
+	if err := LoadState(installedStateFile, state); err != nil {
+		return nil, err
+	}
+	if state.Packages == nil {
+		state.Packages = make(map[string]InstalledRecord)
+	}
+	return state, nil
+}
+
+// RecordInstalled adds pkgs to the record of packages installed by thunderize.
+func RecordInstalled(source string, pkgs []string) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+
+	state, err := LoadInstalledState()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, pkg := range pkgs {
+		if _, ok := state.Packages[pkg]; !ok {
+			state.Packages[pkg] = InstalledRecord{Source: source, InstalledAt: now}
+		}
+	}
+	return SaveState(installedStateFile, state)
+}
+
+// getProtected returns the built-in protected packages plus those in packages/protected.txt.
+func getProtected(fsys fs.FS) (map[string]bool, error) {
+	protected := toSet(ProtectedPackages)
+
+	if _, err := fs.Stat(fsys, ProtectedListFile); err != nil {
+		return protected, nil
+	}
+	names, err := ReadPackageList(fsys, ProtectedListFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		protected[name] = true
+	}
+	return protected, nil
+}
+
+// listedNames returns every package name in any list, regardless of conditions or source.
+func listedNames(fsys fs.FS) (map[string]bool, error) {
+	listed := make(map[string]bool)
+	for _, file := range PackageListFiles {
+		list, err := ParsePackageList(fsys, file.File)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range list.Entries() {
+			listed[entry.Name] = true
+		}
+	}
+	return listed, nil
+}
+
+// PrunePackages removes packages that thunderize installed but that no longer appear in any
+// list, through the backend that installed them. Records of packages their backend no
+// longer has installed are dropped. The plan is shown first and confirmed unless assumeYes
+// is set; protected packages are never removed.
+func PrunePackages(fsys fs.FS, assumeYes bool) error {
+	Print.NewLns(StyleInfoC, "Planning package prune...")
+
+	state, err := LoadInstalledState()
+	if err != nil {
+		return err
+	}
+	listed, err := listedNames(fsys)
+	if err != nil {
+		return err
+	}
+	protected, err := getProtected(fsys)
+	if err != nil {
+		return err
+	}
+	gone, err := uninstalledRecords(state)
+	if err != nil {
+		return err
+	}
+
+	var remove, kept []string
+	changed := false
+	for pkg := range state.Packages {
+		switch {
+		case gone[pkg]:
+			delete(state.Packages, pkg)
+			changed = true
+		case listed[pkg]:
+		case protected[pkg]:
+			kept = append(kept, pkg)
+		default:
+			remove = append(remove, pkg)
+		}
+	}
+	slices.Sort(remove)
+	slices.Sort(kept)
+
+	if changed {
+		if err := SaveState(installedStateFile, state); err != nil {
+			return err
+		}
+	}
+
+	for _, pkg := range kept {
+		fmt.Printf("  %s %s\n", pkg, Dim("(protected, kept)"))
+	}
+
+	if len(remove) == 0 {
+		Print.Success("Nothing to prune!")
+		return nil
+	}
+
+	fmt.Printf("%s %s will be removed:\n", Dim("→"), pluralize(len(remove), "package"))
+	for _, pkg := range remove {
+		fmt.Printf("  %s %s\n", BoldRed("-"), pkg)
+	}
+	Print.Info()
+
+	if !assumeYes {
+		fmt.Print("Remove these packages? (y/N): ")
+		reader := bufio.NewReader(os.Stdin)
+		resp, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read user input: %w", err)
+		}
+		resp = strings.ToLower(strings.TrimSpace(resp))
+		if resp != "y" && resp != "yes" {
+			Print.InfoC("Prune cancelled.")
+			return nil
+		}
+	}
+
+	bySource := make(map[string][]string)
+	for _, pkg := range remove {
+		source := recordSource(state.Packages[pkg])
+		bySource[source] = append(bySource[source], pkg)
+	}
+
+	for _, source := range slices.Sorted(maps.Keys(bySource)) {
+		backend, err := GetBackend(source)
+		if err != nil {
+			return err
+		}
+		if err := backend.Remove(bySource[source]); err != nil {
+			return fmt.Errorf("%s removal failed: %w", source, err)
+		}
+
+		for _, pkg := range bySource[source] {
+			delete(state.Packages, pkg)
+		}
+		if err := SaveState(installedStateFile, state); err != nil {
+			return err
+		}
+	}
+
+	Print.Beforeln(StyleSuccess, fmt.Sprintf("Pruned %s", pluralize(len(remove), "package")))
+	return nil
+}
+
+// recordSource returns the source whose backend manages a recorded package. AUR packages
+// are queried and removed with pacman, so no AUR helper is needed.
+func recordSource(record InstalledRecord) string {
+	if record.Source == SourceAUR {
+		return SourcePacman
+	}
+	return record.Source
+}
+
+// uninstalledRecords returns the recorded packages that the backend of each record no
+// longer has installed. A backend that isn't available has nothing installed.
+func uninstalledRecords(state *InstalledState) (map[string]bool, error) {
+	bySource := make(map[string][]string)
+	for pkg, record := range state.Packages {
+		source := recordSource(record)
+		bySource[source] = append(bySource[source], pkg)
+	}
+
+	gone := make(map[string]bool)
+	for _, source := range slices.Sorted(maps.Keys(bySource)) {
+		pkgs := bySource[source]
+		missing, err := missingFromSource(source, pkgs)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range missing {
+			gone[pkg] = true
+		}
+	}
+	return gone, nil
+}
+
+// missingFromSource returns the pkgs the backend for source doesn't have installed. Flatpak
+// apps count as installed in either scope.
+func missingFromSource(source string, pkgs []string) ([]string, error) {
+	backends := []Backend{}
+	if source == SourceFlatpak {
+		for _, scope := range []FlatpakScope{FlatpakUser, FlatpakSystem} {
+			backends = append(backends, flatpakBackend{scope: scope, remote: DefaultFlatpakRemote})
+		}
+	} else {
+		backend, err := GetBackend(source)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, backend)
+	}
+
+	missing := pkgs
+	for _, backend := range backends {
+		if !backend.Available() {
+			continue
+		}
+		stillMissing, err := backend.Missing(missing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check installed %s packages: %w", source, err)
+		}
+		missing = stillMissing
+	}
+	return missing, nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeCommands puts empty executables with the given names first on PATH, so backends
+// report themselves available while a RecordingRunner answers their queries.
+func fakeCommands(t *testing.T, names ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestUninstalledRecordsAsksEachBackend(t *testing.T) {
+	fakeCommands(t, "dnf", "flatpak")
+	useRunner(t, &RecordingRunner{Respond: func(c Cmd) ([]byte, error) {
+		switch {
+		case c.Name == "rpm":
+			return []byte("bash\nvim\n"), nil
+		case c.Name == "flatpak" && slices.Contains(c.Args, "--system"):
+			return []byte("org.example.System\n"), nil
+		case c.Name == "flatpak":
+			return []byte("org.example.User\n"), nil
+		}
+		t.Errorf("unexpected command %s", c)
+		return nil, nil
+	}})
+
+	state := &InstalledState{Packages: map[string]InstalledRecord{
+		"vim":                {Source: SourceDnf},
+		"htop":               {Source: SourceDnf},
+		"org.example.User":   {Source: SourceFlatpak},
+		"org.example.System": {Source: SourceFlatpak},
+		"org.example.Gone":   {Source: SourceFlatpak},
+	}}
+
+	gone, err := uninstalledRecords(state)
+	if err != nil {
+		t.Fatalf("uninstalledRecords: %v", err)
+	}
+	got := slices.Sorted(maps.Keys(gone))
+	if want := []string{"htop", "org.example.Gone"}; !slices.Equal(got, want) {
+		t.Errorf("uninstalled = %s, want %s", strings.Join(got, ", "), strings.Join(want, ", "))
+	}
+}
@@ -0,0 +1,502 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// reportsDir is the state subdirectory run reports are saved in.
+const reportsDir = "runs"
+
+// RunReport is the structured record of an install or deploy run.
+type RunReport struct {
+	ID       string          `json:"id"`
+	Command  string          `json:"command"`
+	Started  time.Time       `json:"started"`
+	Duration time.Duration   `json:"duration"`
+	Error    string          `json:"error,omitempty"`
+	Steps    []ReportStep    `json:"steps"`
+	Packages []PackageResult `json:"packages,omitempty"`
+	Configs  []ConfigResult  `json:"configs,omitempty"`
+	Warnings []string        `json:"warnings,omitempty"`
+}
+
+// Succeeded reports whether the run finished without an error.
+func (r *RunReport) Succeeded() bool {
+	return r.Error == ""
+}
+
+// ReportStep is a named phase of a run with the commands it ran. Steps started inside
+// another step have a greater Depth.
+type ReportStep struct {
+	Name     string          `json:"name"`
+	Depth    int             `json:"depth,omitempty"`
+	Started  time.Time       `json:"started"`
+	Duration time.Duration   `json:"duration"`
+	Error    string          `json:"error,omitempty"`
+	Commands []CommandRecord `json:"commands,omitempty"`
+}
+
+// CommandRecord is an external command run during a step.
+type CommandRecord struct {
+	Command  string        `json:"command"`
+	Dir      string        `json:"dir,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// ConfigStatus is the outcome of deploying a config.
+type ConfigStatus string
+
+const (
+	ConfigDeployed  ConfigStatus = "deployed"
+	ConfigUnchanged ConfigStatus = "unchanged" // The target already matched and was left alone
+	ConfigFailed    ConfigStatus = "failed"
+)
+
+// ConfigResult records a config deployed during a run.
+type ConfigResult struct {
+	Name   string       `json:"name"`
+	Target string       `json:"target"`
+	Status ConfigStatus `json:"status,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// Result returns the config's status, or its error when the deploy failed. Reports saved
+// before statuses were recorded count as deployed.
+func (c ConfigResult) Result() string {
+	switch {
+	case c.Error != "":
+		return c.Error
+	case c.Status == "":
+		return string(ConfigDeployed)
+	}
+	return string(c.Status)
+}
+
+// report is the run being recorded, nil outside RecordRun.
+var (
+	report   *RunReport
+	reportMu sync.Mutex
+	steps    []int // Indexes into report.Steps of the active steps, innermost last
+)
+
+// withReport calls fn with the current report locked, doing nothing when no run is recorded.
+func withReport(fn func(r *RunReport)) {
+	reportMu.Lock()
+	defer reportMu.Unlock()
+	if report != nil {
+		fn(report)
+	}
+}
+
+// RecordRun runs fn as command, recording its steps, commands, package results, deployed
+// configs and warnings, and saves the report under the state directory's runs/ when done.
+// Dry runs only print where the report would be saved.
+func RecordRun(command string, fn func() error) error {
+	started := time.Now()
+	finished := &RunReport{
+		ID:      started.Format("20060102-150405"),
+		Command: command,
+		Started: started,
+	}
+
+	err := recordSteps(finished, command, fn)
+
+	finished.Duration = time.Since(started)
+	if err != nil {
+		finished.Error = err.Error()
+	}
+	if saveErr := SaveReport(finished); saveErr != nil {
+		Print.Warn(fmt.Sprintf("Warning: Failed to save run report: %v", saveErr))
+	}
+	return err
+}
+
+// recordSteps runs fn as the first step of r with every command recorded. The runner and the
+// current report are restored even when fn panics.
+func recordSteps(r *RunReport, command string, fn func() error) error {
+	reportMu.Lock()
+	report = r
+	reportMu.Unlock()
+
+	inner := runner
+	runner = reportingRunner{inner: inner}
+	defer func() {
+		runner = inner
+		reportMu.Lock()
+		report, steps = nil, nil
+		reportMu.Unlock()
+	}()
+
+	return RunStep(command, fn)
+}
+
+// RunStep runs fn as a named step of the current report. Outside RecordRun it just runs fn.
+func RunStep(name string, fn func() error) error {
+	withReport(func(r *RunReport) {
+		r.Steps = append(r.Steps, ReportStep{Name: name, Depth: len(steps), Started: time.Now()})
+		steps = append(steps, len(r.Steps)-1)
+	})
+
+	err := fn()
+
+	withReport(func(r *RunReport) {
+		step := &r.Steps[steps[len(steps)-1]]
+		steps = steps[:len(steps)-1]
+		step.Duration = time.Since(step.Started)
+		if err != nil {
+			step.Error = err.Error()
+		}
+	})
+	return err
+}
+
+// recordCommand adds a command to the innermost active step.
+func recordCommand(record CommandRecord) {
+	withReport(func(r *RunReport) {
+		if len(steps) > 0 {
+			step := &r.Steps[steps[len(steps)-1]]
+			step.Commands = append(step.Commands, record)
+		}
+	})
+}
+
+// recordPackages adds the outcome of an install to the current report.
+func recordPackages(results []PackageResult) {
+	withReport(func(r *RunReport) {
+		r.Packages = append(r.Packages, results...)
+	})
+}
+
+// recordConfig adds a deployed config to the current report. unchanged is set when the
+// target already matched and wasn't written.
+func recordConfig(name, target string, unchanged bool, err error) {
+	withReport(func(r *RunReport) {
+		result := ConfigResult{Name: name, Target: target, Status: ConfigDeployed}
+		switch {
+		case err != nil:
+			result.Status, result.Error = ConfigFailed, err.Error()
+		case unchanged:
+			result.Status = ConfigUnchanged
+		}
+		r.Configs = append(r.Configs, result)
+	})
+}
+
+// recordWarning adds a warning printed during the run to the current report.
+func recordWarning(msg string) {
+	withReport(func(r *RunReport) {
+		r.Warnings = append(r.Warnings, msg)
+	})
+}
+
+// reportingRunner records the commands that change the system (Run and CombinedOutput) in
+// the current report. Read-only queries aren't recorded.
+type reportingRunner struct {
+	inner Runner
+}
+
+func (r reportingRunner) record(c Cmd, started time.Time, err error) {
+	record := CommandRecord{Command: c.String(), Dir: c.Dir, Duration: time.Since(started)}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	recordCommand(record)
+}
+
+func (r reportingRunner) Run(c Cmd) error {
+	started := time.Now()
+	err := r.inner.Run(c)
+	r.record(c, started, err)
+	return err
+}
+
+func (r reportingRunner) Output(c Cmd) ([]byte, error) {
+	return r.inner.Output(c)
+}
+
+func (r reportingRunner) CombinedOutput(c Cmd) ([]byte, error) {
+	started := time.Now()
+	out, err := r.inner.CombinedOutput(c)
+	r.record(c, started, err)
+	return out, err
+}
+
+// SaveReport writes a report as JSON to runs/<id>.json in the state directory, adding a
+// numeric suffix to the ID when another run started in the same second.
+func SaveReport(r *RunReport) error {
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(stateDir, reportsDir)
+	if err := ensureDir(dir); err != nil {
+		return fmt.Errorf("failed to create reports directory: %w", err)
+	}
+
+	id := r.ID
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(dir, id+".json")); os.IsNotExist(err) {
+			break
+		}
+		id = fmt.Sprintf("%s-%d", r.ID, n)
+	}
+	r.ID = id
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	return AtomicWriteFile(filepath.Join(dir, id+".json"), append(data, '\n'), 0644)
+}
+
+// ListReports returns the IDs of saved reports, oldest first.
+func ListReports() ([]string, error) {
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(stateDir, reportsDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(files))
+	for i, file := range files {
+		ids[i] = strings.TrimSuffix(filepath.Base(file), ".json")
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// LoadReport reads a saved report by ID, or the most recent one for "last" or "".
+func LoadReport(id string) (*RunReport, error) {
+	if id == "" || id == "last" {
+		ids, err := ListReports()
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("no run reports found - reports are saved by install, deploy, setup and bootstrap")
+		}
+		id = ids[len(ids)-1]
+	}
+
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(stateDir, reportsDir, id+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no run report %q (see 'thunderize report list')", id)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read report %s: %w", id, err)
+	}
+
+	var r RunReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", id, err)
+	}
+	return &r, nil
+}
+
+// ShowReports lists saved reports, most recent first.
+func ShowReports() error {
+	ids, err := ListReports()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		Print.Dimmed("No run reports yet.")
+		return nil
+	}
+
+	rows := make([][]string, 0, len(ids))
+	for _, id := range slices.Backward(ids) {
+		r, err := LoadReport(id)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, []string{r.ID, r.Command, r.Started.Format(time.DateTime), formatDuration(r.Duration), reportStatus(r)})
+	}
+	fmt.Println(newTable("ID", "Command", "Started", "Duration", "Status").Rows(rows...))
+	return nil
+}
+
+// ShowReport prints a saved report as tables, or as Markdown when markdown is set.
+func ShowReport(id string, markdown bool) error {
+	r, err := LoadReport(id)
+	if err != nil {
+		return err
+	}
+	if markdown {
+		fmt.Print(ReportMarkdown(r))
+		return nil
+	}
+
+	Print.NewLns(StyleInfoC, fmt.Sprintf("Run %s: %s", r.ID, r.Command))
+	fmt.Printf("%s %s\n", Dim("Started: "), r.Started.Format(time.DateTime))
+	fmt.Printf("%s %s\n", Dim("Duration:"), formatDuration(r.Duration))
+	fmt.Printf("%s %s\n", Dim("Status:  "), reportStatus(r))
+	if r.Error != "" {
+		fmt.Printf("%s %s\n", Dim("Error:   "), BoldRed(r.Error))
+	}
+
+	Print.Beforeln(StyleInfo, Bold("Steps"))
+	stepTable := newTable("Step", "Duration", "Commands", "Result")
+	for _, step := range r.Steps {
+		result := BoldGreen("ok")
+		if step.Error != "" {
+			result = BoldRed(step.Error)
+		}
+		stepTable.Row(strings.Repeat("  ", step.Depth)+step.Name, formatDuration(step.Duration), fmt.Sprint(len(step.Commands)), result)
+	}
+	fmt.Println(stepTable)
+
+	if len(r.Packages) > 0 {
+		Print.Beforeln(StyleInfo, Bold("Packages"))
+		packages := newTable("Package", "Source", "Status", "Reason")
+		for _, pkg := range r.Packages {
+			if pkg.Status == StatusPresent {
+				continue
+			}
+			packages.Row(pkg.Name, pkg.Source, string(pkg.Status), pkg.Reason)
+		}
+		fmt.Println(packages)
+		fmt.Println(Dim(packageCounts(r.Packages)))
+	}
+
+	if len(r.Configs) > 0 {
+		Print.Beforeln(StyleInfo, Bold("Configs"))
+		configs := newTable("Config", "Target", "Result")
+		for _, config := range r.Configs {
+			result := BoldGreen(config.Result())
+			switch {
+			case config.Error != "":
+				result = BoldRed(config.Result())
+			case config.Status == ConfigUnchanged:
+				result = Dim(config.Result())
+			}
+			configs.Row(config.Name, config.Target, result)
+		}
+		fmt.Println(configs)
+	}
+
+	if len(r.Warnings) > 0 {
+		Print.Beforeln(StyleInfo, Bold("Warnings"))
+		for _, warning := range r.Warnings {
+			fmt.Printf("  %s %s\n", BoldYellow("!"), warning)
+		}
+	}
+	return nil
+}
+
+// ReportMarkdown renders a report as Markdown, with every command run.
+func ReportMarkdown(r *RunReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Command)
+	fmt.Fprintf(&b, "- Run: `%s`\n", r.ID)
+	fmt.Fprintf(&b, "- Started: %s\n", r.Started.Format(time.DateTime))
+	fmt.Fprintf(&b, "- Duration: %s\n", formatDuration(r.Duration))
+	fmt.Fprintf(&b, "- Status: %s\n", reportStatus(r))
+	if r.Error != "" {
+		fmt.Fprintf(&b, "- Error: %s\n", markdownCell(r.Error))
+	}
+
+	b.WriteString("\n## Steps\n\n| Step | Duration | Result |\n| --- | --- | --- |\n")
+	for _, step := range r.Steps {
+		result := "ok"
+		if step.Error != "" {
+			result = step.Error
+		}
+		fmt.Fprintf(&b, "| %s%s | %s | %s |\n", strings.Repeat("&nbsp;&nbsp;", step.Depth), markdownCell(step.Name),
+			formatDuration(step.Duration), markdownCell(result))
+	}
+
+	b.WriteString("\n## Commands\n\n")
+	for _, step := range r.Steps {
+		for _, c := range step.Commands {
+			status := "ok"
+			if c.Error != "" {
+				status = "failed: " + c.Error
+			}
+			fmt.Fprintf(&b, "- `%s` (%s, %s, %s)\n", c.Command, step.Name, formatDuration(c.Duration), status)
+		}
+	}
+
+	if len(r.Packages) > 0 {
+		fmt.Fprintf(&b, "\n## Packages\n\n%s\n\n| Package | Source | Status | Reason |\n| --- | --- | --- | --- |\n", packageCounts(r.Packages))
+		for _, pkg := range r.Packages {
+			if pkg.Status != StatusPresent {
+				fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", pkg.Name, pkg.Source, pkg.Status, markdownCell(pkg.Reason))
+			}
+		}
+	}
+
+	if len(r.Configs) > 0 {
+		b.WriteString("\n## Configs\n\n| Config | Target | Result |\n| --- | --- | --- |\n")
+		for _, config := range r.Configs {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", config.Name, config.Target, markdownCell(config.Result()))
+		}
+	}
+
+	if len(r.Warnings) > 0 {
+		b.WriteString("\n## Warnings\n\n")
+		for _, warning := range r.Warnings {
+			fmt.Fprintf(&b, "- %s\n", warning)
+		}
+	}
+	return b.String()
+}
+
+// newTable returns a lipgloss table with the CLI's header style.
+func newTable(headers ...string) *table.Table {
+	return table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(dimmed).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			style := lipgloss.NewStyle().Padding(0, 1)
+			if row == table.HeaderRow {
+				return style.Bold(true)
+			}
+			return style
+		}).
+		Headers(headers...)
+}
+
+func reportStatus(r *RunReport) string {
+	status := "succeeded"
+	if !r.Succeeded() {
+		status = "failed"
+	}
+	return status
+}
+
+func packageCounts(results []PackageResult) string {
+	counts := make(StatusCounts)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	return counts.String()
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+}
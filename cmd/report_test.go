@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRecordRunRestoresRunnerAfterPanic(t *testing.T) {
+	rec := &RecordingRunner{}
+	useRunner(t, rec)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("RecordRun swallowed the panic")
+			}
+		}()
+		_ = RecordRun("test", func() error { panic("boom") })
+	}()
+
+	if runner != Runner(rec) {
+		t.Errorf("runner = %T, want the RecordingRunner back", runner)
+	}
+	if report != nil || steps != nil {
+		t.Error("report still active after RecordRun returned")
+	}
+}
+
+func TestRecordConfigSeparatesUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	source, target := filepath.Join(dir, "repo-inputrc"), filepath.Join(dir, "inputrc")
+	if err := os.WriteFile(source, []byte("set editing-mode vi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &RunReport{}
+	err := recordSteps(r, "deploy", func() error {
+		for range 2 {
+			unchanged, err := RunRsync(source, target, "inputrc", "Deploying", true, nil)
+			recordConfig("inputrc", target, unchanged, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []ConfigStatus
+	for _, config := range r.Configs {
+		got = append(got, config.Status)
+	}
+	if want := []ConfigStatus{ConfigDeployed, ConfigUnchanged}; !slices.Equal(got, want) {
+		t.Errorf("recorded %v, want %v", got, want)
+	}
+}
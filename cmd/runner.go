@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Cmd is an external command to run.
+type Cmd struct {
+	Name    string
+	Args    []string
+	Dir     string        // Working directory ("" for the current one)
+	Env     []string      // KEY=value pairs added to the inherited environment
+	Output  io.Writer     // Where Run sends stdout and stderr instead of the terminal (nil for the terminal)
+	Timeout time.Duration // Kill the command and its children after this long (0 for no limit)
+}
+
+// Command returns a Cmd for name and args, like exec.Command.
+func Command(name string, args ...string) Cmd {
+	return Cmd{Name: name, Args: args}
+}
+
+// InDir returns a copy of c that runs in dir.
+func (c Cmd) InDir(dir string) Cmd {
+	c.Dir = dir
+	return c
+}
+
+// WithEnv returns a copy of c with KEY=value pairs added to its environment.
+func (c Cmd) WithEnv(env ...string) Cmd {
+	c.Env = append(append([]string{}, c.Env...), env...)
+	return c
+}
+
+// WithOutput returns a copy of c whose Run writes stdout and stderr to w, detached from the
+// terminal.
+func (c Cmd) WithOutput(w io.Writer) Cmd {
+	c.Output = w
+	return c
+}
+
+// WithTimeout returns a copy of c that is killed, with its children, after d.
+func (c Cmd) WithTimeout(d time.Duration) Cmd {
+	c.Timeout = d
+	return c
+}
+
+// String formats c as a shell command line, quoting arguments where needed.
+func (c Cmd) String() string {
+	words := make([]string, 0, len(c.Env)+len(c.Args)+1)
+	for _, kv := range c.Env {
+		words = append(words, shellQuote(kv))
+	}
+	words = append(words, shellQuote(c.Name))
+	for _, arg := range c.Args {
+		words = append(words, shellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote single-quotes s when it contains characters the shell would interpret.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`*?[]{}()<>|&;#~!") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Runner executes external commands. Every subsystem runs commands through the package
+// runner (see SetRunner) so they can be faked in tests or printed in dry-run mode.
+type Runner interface {
+	// Run runs c attached to the terminal.
+	Run(c Cmd) error
+	// Output runs a read-only query and returns its standard output.
+	Output(c Cmd) ([]byte, error)
+	// CombinedOutput runs c and returns its standard output and error interleaved.
+	CombinedOutput(c Cmd) ([]byte, error)
+}
+
+var (
+	runner Runner = ExecRunner{}
+	dryRun bool
+)
+
+// SetRunner replaces the runner used for every external command.
+func SetRunner(r Runner) {
+	runner = r
+}
+
+// SetDryRun switches to a DryRunRunner that prints commands instead of running them and
+// makes file writes report what they would change.
+func SetDryRun(enabled bool) {
+	dryRun = enabled
+	if enabled {
+		runner = DryRunRunner{Out: os.Stdout, Queries: ExecRunner{}}
+	} else {
+		runner = ExecRunner{}
+	}
+}
+
+// IsDryRun reports whether dry-run mode is enabled.
+func IsDryRun() bool {
+	return dryRun
+}
+
+// printDryRun reports an action skipped in dry-run mode.
+func printDryRun(format string, args ...any) {
+	fmt.Printf("%s %s\n", Dim("[dry-run]"), fmt.Sprintf(format, args...))
+}
+
+// CommandError is a failed command with the tail of what it wrote to stderr.
+type CommandError struct {
+	Cmd    Cmd
+	Err    error
+	Stderr string
+}
+
+func (e *CommandError) Error() string {
+	if line := lastLine([]byte(e.Stderr), nil); line != "" {
+		return fmt.Sprintf("%v: %s", e.Err, line)
+	}
+	return e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// stderrTailSize is how much of a command's stderr CommandError keeps.
+const stderrTailSize = 4096
+
+// tailWriter keeps the last stderrTailSize bytes written to it.
+type tailWriter struct {
+	buf []byte
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	if len(w.buf) > stderrTailSize {
+		w.buf = w.buf[len(w.buf)-stderrTailSize:]
+	}
+	return len(p), nil
+}
+
+// ExecRunner runs commands with os/exec.
+type ExecRunner struct{}
+
+// command returns the exec.Cmd for c and a function that releases its timeout, which also
+// turns an expired timeout into a clearer error. Commands with a timeout run in their own
+// process group so builds they spawn are killed with them.
+func (ExecRunner) command(c Cmd) (*exec.Cmd, func(error) error) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if c.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+	}
+
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Dir = c.Dir
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	if c.Timeout > 0 {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Cancel = func() error {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+		cmd.WaitDelay = 5 * time.Second
+	}
+
+	return cmd, func(err error) error {
+		defer cancel()
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s: %w", c.Timeout, context.DeadlineExceeded)
+		}
+		return err
+	}
+}
+
+// Run streams the command's output to the terminal, or to c.Output. Failures are returned as
+// a *CommandError carrying the last line the command wrote to stderr.
+func (r ExecRunner) Run(c Cmd) error {
+	var stderr tailWriter
+	cmd, done := r.command(c)
+	if c.Output != nil {
+		cmd.Stdout = c.Output
+		cmd.Stderr = io.MultiWriter(c.Output, &stderr)
+	} else {
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	}
+	if err := done(cmd.Run()); err != nil {
+		return &CommandError{Cmd: c, Err: err, Stderr: string(stderr.buf)}
+	}
+	return nil
+}
+
+// Output returns the command's standard output. Failures are returned as a *CommandError
+// carrying what the command wrote to stderr.
+func (r ExecRunner) Output(c Cmd) ([]byte, error) {
+	cmd, done := r.command(c)
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return out, &CommandError{Cmd: c, Err: done(err), Stderr: string(exitErr.Stderr)}
+	}
+	return out, done(err)
+}
+
+func (r ExecRunner) CombinedOutput(c Cmd) ([]byte, error) {
+	cmd, done := r.command(c)
+	out, err := cmd.CombinedOutput()
+	return out, done(err)
+}
+
+// DryRunRunner prints the commands that would run, with their working directory and
+// environment, instead of running them. Read-only queries still run through Queries so
+// plans reflect the real system.
+type DryRunRunner struct {
+	Out     io.Writer
+	Queries Runner
+}
+
+func (r DryRunRunner) print(c Cmd) {
+	line := "$ " + c.String()
+	if c.Dir != "" {
+		line = fmt.Sprintf("(in %s) %s", c.Dir, line)
+	}
+	fmt.Fprintf(r.Out, "%s %s\n", Dim("[dry-run]"), line)
+}
+
+func (r DryRunRunner) Run(c Cmd) error {
+	r.print(c)
+	return nil
+}
+
+func (r DryRunRunner) Output(c Cmd) ([]byte, error) {
+	return r.Queries.Output(c)
+}
+
+func (r DryRunRunner) CombinedOutput(c Cmd) ([]byte, error) {
+	r.print(c)
+	return nil, nil
+}
+
+// RecordingRunner records commands instead of running them, for tests.
+type RecordingRunner struct {
+	// Respond returns the output and error for a command. When nil every command succeeds
+	// with no output.
+	Respond func(c Cmd) ([]byte, error)
+
+	mu       sync.Mutex
+	commands []Cmd
+}
+
+func (r *RecordingRunner) record(c Cmd) ([]byte, error) {
+	r.mu.Lock()
+	r.commands = append(r.commands, c)
+	r.mu.Unlock()
+
+	if r.Respond == nil {
+		return nil, nil
+	}
+	return r.Respond(c)
+}
+
+func (r *RecordingRunner) Run(c Cmd) error {
+	_, err := r.record(c)
+	return err
+}
+
+func (r *RecordingRunner) Output(c Cmd) ([]byte, error) {
+	return r.record(c)
+}
+
+func (r *RecordingRunner) CombinedOutput(c Cmd) ([]byte, error) {
+	return r.record(c)
+}
+
+// Commands returns the commands run so far, in order.
+func (r *RecordingRunner) Commands() []Cmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Cmd{}, r.commands...)
+}
@@ -10,10 +10,11 @@ import (
 // InitSecrets copies the secrets template to ~/.zsh_secrets if it doesn't exist.
 // If the file exists, it prompts the user before overwriting.
 func InitSecrets() error {
-	repoPath, err := ZshSecretsConfig.GetConfigPath(true)
+	repoPath, _, cleanup, err := ZshSecretsConfig.ResolveSource()
 	if err != nil {
 		return err
 	}
+	defer cleanup()
 
 	sysPath, err := ZshSecretsConfig.GetConfigPath(false)
 	if err != nil {
@@ -35,9 +36,12 @@ func InitSecrets() error {
 		}
 	}
 
-	if err := RunRsync(repoPath, sysPath, "zsh-secrets", "Initializing", true, []string{}); err != nil {
+	if _, err := RunRsync(repoPath, sysPath, "zsh-secrets", "Initializing", true, []string{}); err != nil {
 		return err
 	}
+	if dryRun {
+		return nil
+	}
 	if err := os.Chmod(sysPath, 0600); err != nil {
 		return fmt.Errorf("failed to set secure permissions: %w", err)
 	}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ConfigSource identifies where config files are read from when deploying.
+type ConfigSource string
+
+const (
+	// ConfigSourceAuto uses the on-disk repo when present and falls back to the embedded tree.
+	ConfigSourceAuto ConfigSource = "auto"
+	// ConfigSourceRepo always reads configs from the on-disk repo.
+	ConfigSourceRepo ConfigSource = "repo"
+	// ConfigSourceEmbedded always reads configs from the tree embedded in the binary.
+	ConfigSourceEmbedded ConfigSource = "embedded"
+)
+
+var (
+	embeddedConfigs fs.FS
+	configSource    = ConfigSourceAuto
+)
+
+// SetEmbeddedConfigs registers the config tree embedded in the binary.
+//
+// The filesystem is expected to contain the config/ directory at its root, mirroring the repo layout.
+func SetEmbeddedConfigs(fsys fs.FS) {
+	embeddedConfigs = fsys
+}
+
+// SetConfigSource selects where configs are read from (auto, repo or embedded).
+func SetConfigSource(source string) error {
+	switch s := ConfigSource(source); s {
+	case ConfigSourceAuto, ConfigSourceRepo, ConfigSourceEmbedded:
+		configSource = s
+		return nil
+	case "":
+		configSource = ConfigSourceAuto
+		return nil
+	default:
+		return fmt.Errorf("unknown config source: %s (expected auto, repo or embedded)", source)
+	}
+}
+
+// HasEmbedded reports whether the config is available in the embedded tree.
+func (c *ConfigType) HasEmbedded() bool {
+	if embeddedConfigs == nil {
+		return false
+	}
+	_, err := fs.Stat(embeddedConfigs, c.RepoPath)
+	return err == nil
+}
+
+// ResolveSource returns a readable path for the config's repo copy.
+//
+// When the embedded tree is used, the config is extracted to a temporary directory and the
+// returned cleanup function removes it. The cleanup function is never nil.
+func (c *ConfigType) ResolveSource() (string, ConfigSource, func(), error) {
+	noop := func() {}
+
+	if configSource != ConfigSourceEmbedded {
+		repoPath, err := c.GetConfigPath(true)
+		if err != nil {
+			return "", "", noop, err
+		}
+		if _, err := os.Stat(repoPath); err == nil {
+			return repoPath, ConfigSourceRepo, noop, nil
+		}
+		if configSource == ConfigSourceRepo {
+			return "", "", noop, fmt.Errorf("%s config not found at %s", c.Name, repoPath)
+		}
+	}
+
+	if !c.HasEmbedded() {
+		return "", "", noop, fmt.Errorf("%s config not found in repo or embedded configs", c.Name)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "thunderize-"+c.Name+"-")
+	if err != nil {
+		return "", "", noop, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	target := filepath.Join(tmpDir, path.Base(c.RepoPath))
+	if err := extractEmbedded(c.RepoPath, target); err != nil {
+		cleanup()
+		return "", "", noop, err
+	}
+	return target, ConfigSourceEmbedded, cleanup, nil
+}
+
+// extractedMode returns the permissions for an extracted file. embed.FS reports every file as
+// read-only without execute bits, so scripts are also recognized by their shebang.
+func extractedMode(mode fs.FileMode, data []byte) fs.FileMode {
+	if mode&0111 != 0 || bytes.HasPrefix(data, []byte("#!")) {
+		return 0755
+	}
+	return 0644
+}
+
+// extractEmbedded copies a file or directory from the embedded config tree to target,
+// keeping scripts executable.
+func extractEmbedded(root, target string) error {
+	return fs.WalkDir(embeddedConfigs, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to read embedded %s: %w", name, err)
+		}
+
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(dest, 0755)
+		}
+
+		data, err := fs.ReadFile(embeddedConfigs, name)
+		if err != nil {
+			return fmt.Errorf("failed to read embedded %s: %w", name, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("failed to read embedded %s: %w", name, err)
+		}
+		if err := os.WriteFile(dest, data, extractedMode(info.Mode(), data)); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// ResolvePackageLists returns the package lists to read, following the config source: the
+// on-disk repo's packages/ when present (or required), otherwise the embedded lists.
+func ResolvePackageLists(embedded fs.FS) (fs.FS, error) {
+	if configSource == ConfigSourceEmbedded {
+		return embedded, nil
+	}
+
+	repoRoot, err := GetRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(filepath.Join(repoRoot, "packages")); err == nil && info.IsDir() {
+		return os.DirFS(repoRoot), nil
+	}
+	if configSource == ConfigSourceRepo {
+		return nil, fmt.Errorf("package lists not found in %s", repoRoot)
+	}
+	return embedded, nil
+}
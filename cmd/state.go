@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const repoRootFile = "repo"
+
+// GetStateDir returns thunderize's state directory ($XDG_STATE_HOME/thunderize or ~/.local/state/thunderize).
+func GetStateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "thunderize"), nil
+	}
+
+	homeDir, err := GetHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".local", "state", "thunderize"), nil
+}
+
+// RecordRepoRoot stores the repository location so later runs can find it from any binary location.
+func RecordRepoRoot(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve repo path: %w", err)
+	}
+
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	path := filepath.Join(stateDir, repoRootFile)
+	if err := os.WriteFile(path, []byte(abs+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to record repo location: %w", err)
+	}
+	return nil
+}
+
+// GetRecordedRepoRoot returns the repository location stored by RecordRepoRoot, or "" if none was recorded.
+func GetRecordedRepoRoot() (string, error) {
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(stateDir, repoRootFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read recorded repo location: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// LoadState decodes the JSON state file name into v, leaving v untouched when the file doesn't exist.
+func LoadState(name string, v any) error {
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filepath.Join(stateDir, name))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// SaveState atomically writes v as JSON to the state file name.
+func SaveState(name string, v any) error {
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	return AtomicWriteFile(filepath.Join(stateDir, name), append(data, '\n'), 0644)
+}
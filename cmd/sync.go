@@ -1,9 +1,9 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 )
 
@@ -14,16 +14,46 @@ type ConfigType struct {
 	SystemPath string   // Path on system (e.g., "~/.config/nvim")
 	IsFile     bool     // true if config is a single file, false if directory
 	Excludes   []string // rsync exclude patterns
+	Encrypted  bool     // true if the repo copy is age-encrypted (single files only)
 }
 
-// GetRepoRoot returns the repository root directory where the binary is located.
+var repoRootOverride string
+
+// SetRepoRoot forces GetRepoRoot to return dir for the rest of the process.
+func SetRepoRoot(dir string) {
+	repoRootOverride = dir
+}
+
+// GetRepoRoot returns the repository root directory.
+//
+// The root is resolved in order from SetRepoRoot, $THUNDERIZE_REPO, the parent of the binary's
+// directory when it contains config/, and the location recorded by bootstrap. When none apply,
+// the parent of the binary's directory is returned.
 func GetRepoRoot() (string, error) {
+	if repoRootOverride != "" {
+		return repoRootOverride, nil
+	}
+	if dir := os.Getenv("THUNDERIZE_REPO"); dir != "" {
+		return ExpandPath(dir)
+	}
+
 	exe, err := os.Executable()
 	if err != nil {
 		return "", fmt.Errorf("failed to get executable path: %w", err)
 	}
 	exeDir := filepath.Dir(exe)
 	repoRoot := filepath.Dir(exeDir)
+	if info, err := os.Stat(filepath.Join(repoRoot, "config")); err == nil && info.IsDir() {
+		return repoRoot, nil
+	}
+
+	recorded, err := GetRecordedRepoRoot()
+	if err != nil {
+		return "", err
+	}
+	if recorded != "" {
+		return recorded, nil
+	}
 	return repoRoot, nil
 }
 
@@ -66,57 +96,61 @@ func (c *ConfigType) GetConfigPath(isRepo bool) (string, error) {
 	return ExpandPath(c.SystemPath)
 }
 
-// RunRsync executes rsync to synchronize config directories or files.
-func RunRsync(source, target, configName, operation string, isFile bool, excludes []string) error {
-	targetPath := target
-	if isFile {
-		targetPath = filepath.Dir(target)
-	}
-
-	if err := os.MkdirAll(targetPath, 0755); err != nil {
-		return fmt.Errorf("failed to create target directory: %w", err)
+// RunRsync synchronizes config directories or files, and reports whether a single file was
+// left untouched because the target already matched.
+//
+// Single files are copied atomically (temp file, fsync, rename) so an interrupted run never
+// leaves a truncated file behind. Directories are rsynced into a staging copy that replaces
+// the target only once rsync succeeds.
+func RunRsync(source, target, configName, operation string, isFile bool, excludes []string) (bool, error) {
+	if err := ensureDir(filepath.Dir(target)); err != nil {
+		return false, fmt.Errorf("failed to create target directory: %w", err)
 	}
 
 	Print.InfoC(fmt.Sprintf("%s %s config...", operation, configName))
 	fmt.Printf("%s %s\n", Dim("Source:"), source)
 	fmt.Printf("%s %s\n", Dim("Target:"), target)
 
-	args := []string{"-av"}
-
-	if !isFile {
-		args = append(args, "--delete")
+	if isFile {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		if fileHasContents(target, data) {
+			Print.Success(fmt.Sprintf("%s config unchanged", configName))
+			return true, nil
+		}
+		if err := AtomicCopyFile(source, target); err != nil {
+			return false, err
+		}
+		Print.Success(fmt.Sprintf("%s config %s successfully", configName, operation))
+		return false, nil
 	}
 
+	args := []string{"-av", "--delete"}
 	args = append(args, "--exclude=.git", "--exclude=*.swp", "--exclude=*.swo")
 
 	for _, exclude := range excludes {
 		args = append(args, "--exclude="+exclude)
 	}
 
-	if isFile {
-		args = append(args, source, target)
-	} else {
-		args = append(args, source+"/", target+"/")
-	}
-
-	cmd := exec.Command("rsync", args...)
-
-	output, err := cmd.CombinedOutput()
+	output, err := StagedRsync(source, target, args)
 	if err != nil {
-		return fmt.Errorf("rsync failed: %w\nOutput: %s", err, string(output))
+		return false, fmt.Errorf("%w\nOutput: %s", err, string(output))
 	}
 
 	Print.Success(fmt.Sprintf("%s config %s successfully", configName, operation))
-	return nil
+	return false, nil
+}
+
+// fileHasContents reports whether the file at path exists and holds exactly data.
+func fileHasContents(path string, data []byte) bool {
+	existing, err := os.ReadFile(path)
+	return err == nil && bytes.Equal(existing, data)
 }
 
 // SyncConfig synchronizes a config between repo and system.
 func SyncConfig(config *ConfigType, toSystem bool) error {
-	repoPath, err := config.GetConfigPath(true)
-	if err != nil {
-		return err
-	}
-
 	systemPath, err := config.GetConfigPath(false)
 	if err != nil {
 		return err
@@ -124,10 +158,25 @@ func SyncConfig(config *ConfigType, toSystem bool) error {
 
 	var source, target, operation string
 	if toSystem {
+		repoPath, from, cleanup, err := config.ResolveSource()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		fmt.Printf("%s Using %s config source\n", Dim("→"), from)
 		source = repoPath
 		target = systemPath
 		operation = "Deploying"
 	} else {
+		if configSource == ConfigSourceEmbedded {
+			return fmt.Errorf("cannot back up %s into embedded configs", config.Name)
+		}
+
+		repoPath, err := config.GetConfigPath(true)
+		if err != nil {
+			return err
+		}
 		source = systemPath
 		target = repoPath
 		operation = "Backing up"
@@ -137,5 +186,14 @@ func SyncConfig(config *ConfigType, toSystem bool) error {
 		return fmt.Errorf("%s config not found at %s", config.Name, source)
 	}
 
-	return RunRsync(source, target, config.Name, operation, config.IsFile, config.Excludes)
+	var unchanged bool
+	if config.Encrypted {
+		unchanged, err = syncEncrypted(config, source, target, toSystem)
+	} else {
+		unchanged, err = RunRsync(source, target, config.Name, operation, config.IsFile, config.Excludes)
+	}
+	if toSystem {
+		recordConfig(config.Name, target, unchanged, err)
+	}
+	return err
 }
@@ -0,0 +1,359 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// SyncDBDir is where pacman keeps its sync databases.
+var SyncDBDir = "/var/lib/pacman/sync"
+
+// DBPackage is a package entry from a pacman database.
+type DBPackage struct {
+	Name       string
+	Version    string
+	Repository string   // Sync repository, "" for the local database
+	Groups     []string // Groups the package belongs to
+	Provides   []string // Virtual packages it provides, without version constraints
+	Depends    []string // Dependencies, without version constraints
+	Explicit   bool     // Installed explicitly (local database only)
+}
+
+// PackageDB indexes packages from one or more pacman databases.
+type PackageDB struct {
+	Packages map[string]*DBPackage
+	Groups   map[string][]string // Group name to member packages
+	Provides map[string][]string // Provided name to providing packages
+}
+
+func newPackageDB() *PackageDB {
+	return &PackageDB{
+		Packages: make(map[string]*DBPackage),
+		Groups:   make(map[string][]string),
+		Provides: make(map[string][]string),
+	}
+}
+
+// add indexes a package, keeping the first one seen when repositories overlap (pacman's repo order).
+func (db *PackageDB) add(pkg *DBPackage) {
+	if pkg.Name == "" {
+		return
+	}
+	if _, ok := db.Packages[pkg.Name]; ok {
+		return
+	}
+
+	db.Packages[pkg.Name] = pkg
+	for _, group := range pkg.Groups {
+		db.Groups[group] = append(db.Groups[group], pkg.Name)
+	}
+	for _, provided := range pkg.Provides {
+		db.Provides[provided] = append(db.Provides[provided], pkg.Name)
+	}
+}
+
+// Has reports whether name is a package, group or provided name in the database.
+func (db *PackageDB) Has(name string) bool {
+	if _, ok := db.Packages[name]; ok {
+		return true
+	}
+	if _, ok := db.Groups[name]; ok {
+		return true
+	}
+	_, ok := db.Provides[name]
+	return ok
+}
+
+// Suggest returns up to three package or group names close to name, for typos.
+func (db *PackageDB) Suggest(name string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	maxDistance := max(1, min(3, len(name)/4))
+	var candidates []candidate
+	consider := func(other string) {
+		if d := levenshtein(name, other); d <= maxDistance {
+			candidates = append(candidates, candidate{other, d})
+		}
+	}
+	for other := range db.Packages {
+		consider(other)
+	}
+	for other := range db.Groups {
+		consider(other)
+	}
+
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.name, b.name)
+	})
+
+	var names []string
+	for _, c := range candidates[:min(3, len(candidates))] {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// stripConstraint removes a version constraint such as "=5.2" or ">=1.0" from a dependency.
+func stripConstraint(dep string) string {
+	if i := strings.IndexAny(dep, "<>="); i >= 0 {
+		return dep[:i]
+	}
+	return dep
+}
+
+// parseDesc parses a pacman desc file into its %FIELD% sections.
+func parseDesc(r io.Reader) (map[string][]string, error) {
+	fields := make(map[string][]string)
+	var current string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			current = ""
+		case current == "" && strings.HasPrefix(line, "%") && strings.HasSuffix(line, "%"):
+			current = strings.Trim(line, "%")
+		case current != "":
+			fields[current] = append(fields[current], line)
+		}
+	}
+	return fields, scanner.Err()
+}
+
+// packageFromDesc builds a package from parsed desc fields.
+func packageFromDesc(fields map[string][]string, repo string) *DBPackage {
+	first := func(key string) string {
+		if values := fields[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	stripped := func(key string) []string {
+		var values []string
+		for _, value := range fields[key] {
+			values = append(values, stripConstraint(value))
+		}
+		return values
+	}
+
+	return &DBPackage{
+		Name:       first("NAME"),
+		Version:    first("VERSION"),
+		Repository: repo,
+		Groups:     fields["GROUPS"],
+		Provides:   stripped("PROVIDES"),
+		Depends:    stripped("DEPENDS"),
+		Explicit:   first("REASON") != "1",
+	}
+}
+
+// decompress detects the database compression from its magic bytes and returns a reader
+// for the underlying tar stream.
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(6)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		dec, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return io.NopCloser(bzip2.NewReader(br)), nil
+	case bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return nil, fmt.Errorf("xz-compressed databases are not supported")
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// readSyncDB adds every package in a sync database file to db.
+func readSyncDB(db *PackageDB, file, repo string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file, err)
+	}
+	defer f.Close()
+
+	stream, err := decompress(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	defer stream.Close()
+
+	tr := tar.NewReader(stream)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if path.Base(hdr.Name) != "desc" {
+			continue
+		}
+
+		fields, err := parseDesc(tr)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s in %s: %w", hdr.Name, file, err)
+		}
+		db.add(packageFromDesc(fields, repo))
+	}
+}
+
+// syncRepoOrder returns the repositories in pacman.conf order, so lookups match pacman's
+// precedence. Repositories missing from pacman.conf come last in name order.
+func syncRepoOrder(files []string) []string {
+	repos := make([]string, 0, len(files))
+	for _, file := range files {
+		repos = append(repos, strings.TrimSuffix(filepath.Base(file), ".db"))
+	}
+	slices.Sort(repos)
+
+	data, err := os.ReadFile("/etc/pacman.conf")
+	if err != nil {
+		return repos
+	}
+
+	var ordered []string
+	for line := range strings.SplitSeq(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+			continue
+		}
+		name := strings.Trim(line, "[]")
+		if name != "options" && slices.Contains(repos, name) && !slices.Contains(ordered, name) {
+			ordered = append(ordered, name)
+		}
+	}
+	for _, repo := range repos {
+		if !slices.Contains(ordered, repo) {
+			ordered = append(ordered, repo)
+		}
+	}
+	return ordered
+}
+
+// LoadSyncDB reads every sync database in SyncDBDir without calling pacman.
+func LoadSyncDB() (*PackageDB, error) {
+	files, err := filepath.Glob(filepath.Join(SyncDBDir, "*.db"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no sync databases found in %s - run 'sudo pacman -Sy' once", SyncDBDir)
+	}
+
+	db := newPackageDB()
+	for _, repo := range syncRepoOrder(files) {
+		if err := readSyncDB(db, filepath.Join(SyncDBDir, repo+".db"), repo); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// UnknownPackage is a listed package that isn't in the sync databases.
+type UnknownPackage struct {
+	Entry       PackageEntry
+	Suggestions []string
+}
+
+// FindUnknownPackages returns the entries that are neither a package, group nor provided name
+// in the sync databases.
+func FindUnknownPackages(db *PackageDB, entries []PackageEntry) []UnknownPackage {
+	var unknown []UnknownPackage
+	for _, entry := range entries {
+		if !db.Has(entry.Name) {
+			unknown = append(unknown, UnknownPackage{Entry: entry, Suggestions: db.Suggest(entry.Name)})
+		}
+	}
+	return unknown
+}
+
+// printUnknownPackages prints unknown packages with their suggested replacements.
+func printUnknownPackages(unknown []UnknownPackage) {
+	for _, u := range unknown {
+		msg := fmt.Sprintf("  %s %s %s", BoldRed("✗"), u.Entry.Name, Dim(fmt.Sprintf("(%s:%d)", u.Entry.File, u.Entry.Line)))
+		if len(u.Suggestions) > 0 {
+			msg += " did you mean " + strings.Join(u.Suggestions, ", ") + "?"
+		}
+		fmt.Println(msg)
+	}
+}
+
+// ValidatePackages checks every repo package in the lists against the local sync databases.
+func ValidatePackages(fsys fs.FS) error {
+	Print.NewLns(StyleInfoC, "Validating package lists against sync databases...")
+
+	db, err := LoadSyncDB()
+	if err != nil {
+		return err
+	}
+	entries, err := CollectPackages(fsys, SourcePacman)
+	if err != nil {
+		return err
+	}
+
+	unknown := FindUnknownPackages(db, entries)
+	if len(unknown) == 0 {
+		Print.Success(fmt.Sprintf("All %s found in the sync databases!", pluralize(len(entries), "package")))
+		return nil
+	}
+
+	printUnknownPackages(unknown)
+	Print.Info()
+	return fmt.Errorf("%s not found in the sync databases", pluralize(len(unknown), "package"))
+}
@@ -0,0 +1,449 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ToolStatus compares a pinned tool version with the latest available one.
+type ToolStatus struct {
+	Tool   ToolVersion
+	Latest string // "" when the lookup failed
+	Err    error
+}
+
+// Outdated reports whether a newer version than the default pinned one is available.
+func (s ToolStatus) Outdated() bool {
+	return s.Latest != "" && compareVersions(s.Latest, s.Tool.Version()) > 0
+}
+
+// compareVersions compares versions part by part, so 1.10.0 sorts after 1.9.2. Numeric parts
+// compare as numbers, and missing trailing ones count as zero, so 1.0 equals 1.0.0. Alphabetic
+// parts (rc1, beta, a1 in 3.13.0a1) are pre-releases: they sort before any release part or the
+// end of the version, so 1.0.rc1 < 1.0 < 1.0.1, and compare by label and then number, so
+// rc2 < rc10.
+func compareVersions(a, b string) int {
+	as, bs := versionParts(a), versionParts(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		ap, bp := "0", "0"
+		switch {
+		case i >= len(as):
+			if isPreRelease(bs[i]) {
+				return 1
+			}
+			bp = bs[i]
+		case i >= len(bs):
+			if isPreRelease(as[i]) {
+				return -1
+			}
+			ap = as[i]
+		default:
+			ap, bp = as[i], bs[i]
+		}
+		if c := compareVersionPart(ap, bp); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// versionParts splits a version at separators and where digits turn into letters, dropping a
+// leading v: v3.13.0a1 becomes 3, 13, 0, a1.
+func versionParts(v string) []string {
+	if len(v) > 1 && v[0] == 'v' && unicode.IsDigit(rune(v[1])) {
+		v = v[1:]
+	}
+
+	var parts []string
+	for _, field := range strings.FieldsFunc(v, func(r rune) bool { return r == '.' || r == '-' || r == '+' || r == '_' }) {
+		start := 0
+		for i := 1; i < len(field); i++ {
+			if unicode.IsDigit(rune(field[i-1])) && unicode.IsLetter(rune(field[i])) {
+				parts = append(parts, field[start:i])
+				start = i
+			}
+		}
+		parts = append(parts, field[start:])
+	}
+	return parts
+}
+
+// isPreRelease reports whether a version part is alphabetic rather than a release number.
+func isPreRelease(part string) bool {
+	_, err := strconv.Atoi(part)
+	return err != nil
+}
+
+// compareVersionPart compares one part of two versions.
+func compareVersionPart(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return cmp.Compare(an, bn)
+	case aErr == nil:
+		return 1
+	case bErr == nil:
+		return -1
+	}
+
+	aLabel, bLabel := strings.TrimRightFunc(a, unicode.IsDigit), strings.TrimRightFunc(b, unicode.IsDigit)
+	if c := strings.Compare(aLabel, bLabel); c != 0 {
+		return c
+	}
+	an, _ = strconv.Atoi(a[len(aLabel):])
+	bn, _ = strconv.Atoi(b[len(bLabel):])
+	return cmp.Compare(an, bn)
+}
+
+// CheckOutdatedTools looks up the latest version of every tool in config/tool-versions with
+// the selected version manager.
+func CheckOutdatedTools() ([]ToolStatus, error) {
+	manager, err := GetVersionManager()
+	if err != nil {
+		return nil, err
+	}
+	path, err := ToolVersionsPath()
+	if err != nil {
+		return nil, err
+	}
+	tools, err := ReadToolVersions(path)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ToolStatus, len(tools))
+	for i, tool := range tools {
+		latest, err := manager.Latest(tool.Name)
+		statuses[i] = ToolStatus{Tool: tool, Latest: latest, Err: err}
+	}
+	return statuses, nil
+}
+
+// ShowOutdatedTools prints each pinned tool version next to the latest available one.
+func ShowOutdatedTools() error {
+	Print.NewLns(StyleInfoC, "Checking tool versions...")
+
+	statuses, err := CheckOutdatedTools()
+	if err != nil {
+		return err
+	}
+
+	t := newTable("Tool", "Pinned", "Latest", "Status")
+	outdated := 0
+	for _, status := range statuses {
+		pinned := strings.Join(status.Tool.Versions, " ")
+		switch {
+		case status.Err != nil:
+			t.Row(status.Tool.Name, pinned, "?", BoldRed(lastLine(nil, status.Err)))
+		case status.Outdated():
+			outdated++
+			t.Row(status.Tool.Name, pinned, status.Latest, BoldYellow("outdated"))
+		default:
+			t.Row(status.Tool.Name, pinned, status.Latest, BoldGreen("current"))
+		}
+	}
+	fmt.Println(t)
+
+	if outdated == 0 {
+		Print.Beforeln(StyleSuccess, "All tools are current!")
+		return nil
+	}
+	Print.Beforeln(StyleWarn, fmt.Sprintf("%s outdated - update with 'thunderize tools bump <tool>'", pluralize(outdated, "tool")))
+	return nil
+}
+
+// SetToolVersion rewrites the default version of tool in a tool-versions file, keeping comments,
+// the order of entries and the columns of the fields after it. It returns the previous version.
+func SetToolVersion(path, tool, version string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read tool-versions: %w", err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || fields[0] != tool {
+			continue
+		}
+
+		var previous string
+		lines[i], previous = replaceVersion(line, version)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to stat tool-versions: %w", err)
+		}
+		return previous, AtomicWriteFile(path, []byte(strings.Join(lines, "\n")), info.Mode().Perm())
+	}
+	return "", fmt.Errorf("%s is not in %s", tool, path)
+}
+
+// replaceVersion replaces the first version on a tool-versions line. The spaces after it grow
+// or shrink so the following fields keep their column when there's room. It returns the new
+// line and the previous version.
+func replaceVersion(line, version string) (string, string) {
+	blank := func(r rune) bool { return r == ' ' || r == '\t' }
+
+	nameStart := strings.IndexFunc(line, func(r rune) bool { return !blank(r) })
+	nameEnd := nameStart + strings.IndexFunc(line[nameStart:], blank)
+	if nameEnd < nameStart {
+		return line + " " + version, ""
+	}
+
+	start := nameEnd + strings.IndexFunc(line[nameEnd:], func(r rune) bool { return !blank(r) })
+	if start < nameEnd || line[start] == '#' {
+		return line[:nameEnd] + " " + version + line[nameEnd:], ""
+	}
+
+	end := start + strings.IndexFunc(line[start:], blank)
+	if end < start {
+		return line[:start] + version, line[start:]
+	}
+	previous := line[start:end]
+
+	rest := strings.TrimLeftFunc(line[end:], blank)
+	gap := line[end : len(line)-len(rest)]
+	if rest != "" && !strings.Contains(gap, "\t") {
+		gap = strings.Repeat(" ", max(len(gap)+len(previous)-len(version), 1))
+	}
+	return line[:start] + version + gap + rest, previous
+}
+
+// BumpTool pins tool to version in config/tool-versions (the latest version when empty) and
+// installs it with the selected version manager, adding its plugin from packages/plugins.txt.
+func BumpTool(fsys fs.FS, tool, version string) error {
+	manager, err := GetVersionManager()
+	if err != nil {
+		return err
+	}
+	sources, err := ReadPluginSources(fsys)
+	if err != nil {
+		return err
+	}
+	path, err := ToolVersionsPath()
+	if err != nil {
+		return err
+	}
+
+	tools, err := ReadToolVersions(path)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(tools, func(t ToolVersion) bool { return t.Name == tool }) {
+		return fmt.Errorf("%s is not in %s", tool, path)
+	}
+
+	if err := manager.EnsurePlugin(pluginSource(sources, tool)); err != nil {
+		return err
+	}
+	if version == "" {
+		if version, err = manager.Latest(tool); err != nil {
+			return err
+		}
+	}
+
+	previous, err := SetToolVersion(path, tool, version)
+	if err != nil {
+		return err
+	}
+	if previous == version {
+		Print.Success(fmt.Sprintf("%s is already pinned to %s", tool, version))
+	} else {
+		fmt.Printf("%s Pinned %s %s → %s in %s\n", Dim("→"), tool, previous, version, path)
+	}
+
+	fmt.Printf("%s Installing %s %s via %s...\n", Dim("→"), tool, version, manager.Name())
+	if err := runner.Run(manager.InstallCommand(tool, version)); err != nil {
+		return fmt.Errorf("failed to install %s %s: %w", tool, version, err)
+	}
+
+	Print.Beforeln(StyleSuccess, fmt.Sprintf("%s %s installed!", tool, version))
+	return nil
+}
+
+// Defaults for installing tool versions, see SetToolInstallOptions.
+const (
+	DefaultToolJobs    = 2
+	DefaultToolTimeout = 45 * time.Minute
+)
+
+var (
+	toolJobs    = DefaultToolJobs
+	toolTimeout = DefaultToolTimeout
+)
+
+// SetToolInstallOptions sets how many tool versions install at once and how long each
+// install may run before it's killed (0 for no limit).
+func SetToolInstallOptions(jobs int, timeout time.Duration) {
+	toolJobs = max(jobs, 1)
+	toolTimeout = timeout
+}
+
+// ToolResult records what happened to a pinned tool version.
+type ToolResult struct {
+	Tool     ToolVersion
+	Version  string // The version this result is for ("" when none is pinned)
+	Status   InstallStatus
+	Reason   string
+	Duration time.Duration
+	Log      string // Install log ("" when nothing was installed)
+}
+
+// toolLogDir returns a new directory for this run's per-tool install logs.
+func toolLogDir() (string, error) {
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(stateDir, "logs", "tools-"+time.Now().Format("20060102-150405"))
+	if err := ensureDir(dir); err != nil {
+		return "", fmt.Errorf("failed to create log directory: %w", err)
+	}
+	return dir, nil
+}
+
+// InstallToolVersions installs every version of each tool on its own with a pool of toolJobs
+// workers, so one failed build doesn't stop the others. Each install writes to its own log
+// file in logDir and is killed after toolTimeout. Versions already installed are left alone.
+func InstallToolVersions(manager VersionManager, tools []ToolVersion, logDir string) []ToolResult {
+	var jobs []ToolResult
+	for _, tool := range tools {
+		if len(tool.Versions) == 0 {
+			jobs = append(jobs, ToolResult{Tool: tool})
+		}
+		for _, version := range tool.Versions {
+			jobs = append(jobs, ToolResult{Tool: tool, Version: version})
+		}
+	}
+
+	results := make([]ToolResult, len(jobs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(toolJobs, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = installToolVersion(manager, jobs[i], logDir)
+			}
+		}()
+	}
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// installToolVersion installs the version of result's tool, logging its output to a file in
+// logDir, and returns result with the outcome.
+func installToolVersion(manager VersionManager, result ToolResult, logDir string) ToolResult {
+	name, version := result.Tool.Name, result.Version
+	if version == "" {
+		result.Status, result.Reason = StatusSkipped, "no version pinned"
+		return result
+	}
+	if manager.HasVersion(name, version) {
+		result.Status = StatusPresent
+		return result
+	}
+
+	c := manager.InstallCommand(name, version).WithTimeout(toolTimeout)
+	if !IsDryRun() {
+		result.Log = filepath.Join(logDir, toolLogName(name, version))
+		f, err := os.Create(result.Log)
+		if err != nil {
+			result.Status, result.Reason = StatusFailed, fmt.Sprintf("failed to create log: %v", err)
+			return result
+		}
+		defer f.Close()
+		fmt.Fprintf(f, "$ %s\n\n", c)
+		c = c.WithOutput(f)
+	}
+
+	fmt.Printf("%s Installing %s %s...\n", Dim("→"), name, version)
+	started := time.Now()
+	err := runner.Run(c)
+	result.Duration = time.Since(started)
+
+	if err != nil {
+		result.Status, result.Reason = StatusFailed, lastLine(nil, err)
+		fmt.Printf("  %s %s %s %s\n", BoldRed("✗"), name, version, Dim(result.Reason))
+		return result
+	}
+	if IsDryRun() {
+		result.Status = StatusPlanned
+		return result
+	}
+	result.Status = StatusInstalled
+	fmt.Printf("  %s %s %s %s\n", BoldGreen("✓"), name, version, Dim(formatDuration(result.Duration)))
+	return result
+}
+
+// toolLogName returns the log file name for a tool version. Characters other than letters,
+// digits, dots and dashes become underscores, so versions such as path:/opt/go or
+// ref:feature/x stay a single file in the log directory.
+func toolLogName(name, version string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-') {
+			return r
+		}
+		return '_'
+	}, version)
+	return name + "-" + safe + ".log"
+}
+
+// printToolSummary prints a table of every tool version with its outcome and log, then the
+// totals. It returns an error when any install failed.
+func printToolSummary(results []ToolResult) error {
+	counts := make(StatusCounts)
+	t := newTable("Tool", "Version", "Status", "Duration", "Details")
+	for _, result := range results {
+		counts[result.Status]++
+
+		var status, details string
+		switch result.Status {
+		case StatusInstalled:
+			status, details = BoldGreen("installed"), result.Log
+		case StatusPresent:
+			status = Dim("present")
+		case StatusPlanned:
+			status = Dim("planned")
+		case StatusSkipped:
+			status, details = BoldYellow("skipped"), result.Reason
+		case StatusFailed:
+			status, details = BoldRed("failed"), result.Reason
+			if result.Log != "" {
+				details += "\n" + result.Log
+			}
+		}
+
+		duration := ""
+		if result.Duration > 0 {
+			duration = formatDuration(result.Duration)
+		}
+		t.Row(result.Tool.Name, result.Version, status, duration, details)
+	}
+	Print.Info()
+	fmt.Println(t)
+
+	printStatusSummary(counts)
+
+	if counts[StatusFailed] > 0 {
+		return fmt.Errorf("%s failed to install", pluralize(counts[StatusFailed], "tool version"))
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.10.0", "1.9.2", 1},
+		{"1.9.2", "1.10.0", -1},
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.0", "1.0.1", -1},
+		{"1.0", "1.0.0", 0},
+		{"1.0.0", "1", 0},
+		{"1.0", "1.0.0-rc1", 1},
+		{"1.0.rc1", "1.0.1", -1},
+		{"1.0.rc1", "1.0.0", -1},
+		{"1.0-rc1", "1.0", -1},
+		{"1.0", "1.0-beta", 1},
+		{"1.0.0-alpha", "1.0.0-beta", -1},
+		{"1.0.0-rc2", "1.0.0-rc10", -1},
+		{"3.13.0a1", "3.13.0", -1},
+		{"3.13.0a1", "3.13.0b1", -1},
+		{"3.13.0rc1", "3.12.7", 1},
+		{"21.0.1+12", "21.0.1+9", 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSetToolVersionKeepsAlignment(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		version  string
+		want     string
+		previous string
+	}{
+		{"shorter", "nodejs   22.11.0  20.18.0", "23.1.0", "nodejs   23.1.0   20.18.0", "22.11.0"},
+		{"longer", "golang   1.23.2   # primary", "1.23.10", "golang   1.23.10  # primary", "1.23.2"},
+		{"no room", "ruby 3.3.5 3.2.0", "3.3.10", "ruby 3.3.10 3.2.0", "3.3.5"},
+		{"last field", "python   3.12.7", "3.13.0", "python   3.13.0", "3.12.7"},
+		{"tabs", "rust\t1.81.0\t# stable", "1.82.0", "rust\t1.82.0\t# stable", "1.81.0"},
+		{"no version", "zig", "0.13.0", "zig 0.13.0", ""},
+		{"comment only", "zig # nightly", "0.13.0", "zig 0.13.0 # nightly", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tool-versions")
+			data := "# tools\n" + tt.line + "\nterraform 1.9.8\n"
+			if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			previous, err := SetToolVersion(path, strings.Fields(tt.line)[0], tt.version)
+			if err != nil {
+				t.Fatalf("SetToolVersion: %v", err)
+			}
+			if previous != tt.previous {
+				t.Errorf("previous = %q, want %q", previous, tt.previous)
+			}
+			got, _ := os.ReadFile(path)
+			if want := "# tools\n" + tt.want + "\nterraform 1.9.8\n"; string(got) != want {
+				t.Errorf("file = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestInstallToolVersionLogsNonReleaseVersions(t *testing.T) {
+	fakeCommands(t, "mise")
+	useRunner(t, &RecordingRunner{Respond: func(c Cmd) ([]byte, error) {
+		if c.Args[0] == "where" {
+			return nil, errors.New("not installed")
+		}
+		return nil, nil
+	}})
+
+	logDir := t.TempDir()
+	for _, version := range []string{"path:/opt/go", "ref:feature/x", ".."} {
+		result := installToolVersion(miseManager{}, ToolResult{Tool: ToolVersion{Name: "golang"}, Version: version}, logDir)
+		if result.Status != StatusInstalled {
+			t.Errorf("%s: %s (%s), want installed", version, result.Status, result.Reason)
+			continue
+		}
+		if filepath.Dir(result.Log) != logDir {
+			t.Errorf("%s: log %s isn't in the log directory", version, result.Log)
+		}
+		if _, err := os.Stat(result.Log); err != nil {
+			t.Errorf("%s: %v", version, err)
+		}
+	}
+}
+
+func TestInstallToolVersionsInstallsEveryVersion(t *testing.T) {
+	fakeCommands(t, "mise")
+	rec := &RecordingRunner{Respond: func(c Cmd) ([]byte, error) {
+		switch {
+		case c.Args[0] == "where" && c.Args[1] != "nodejs@20.18.0":
+			return nil, errors.New("not installed")
+		case c.Args[0] == "install" && slices.Contains(c.Args, "python@3.12.7"):
+			return nil, errors.New("build failed")
+		}
+		return nil, nil
+	}}
+	useRunner(t, rec)
+
+	tools := []ToolVersion{
+		{Name: "nodejs", Versions: []string{"22.11.0", "20.18.0"}},
+		{Name: "python", Versions: []string{"3.13.0", "3.12.7"}},
+		{Name: "rust"},
+	}
+	results := InstallToolVersions(miseManager{}, tools, t.TempDir())
+
+	var got []string
+	for _, result := range results {
+		got = append(got, result.Tool.Name+" "+result.Version+" "+string(result.Status))
+	}
+	want := []string{
+		"nodejs 22.11.0 installed",
+		"nodejs 20.18.0 present",
+		"python 3.13.0 installed",
+		"python 3.12.7 failed",
+		"rust  skipped",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("results:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	err := printToolSummary(results)
+	if err == nil || !strings.Contains(err.Error(), "1 tool version failed") {
+		t.Errorf("printToolSummary = %v, want one failed version", err)
+	}
+}
+
+func TestPrintToolSummaryIgnoresSkipped(t *testing.T) {
+	results := []ToolResult{
+		{Tool: ToolVersion{Name: "rust"}, Status: StatusSkipped, Reason: "no version pinned"},
+		{Tool: ToolVersion{Name: "nodejs"}, Version: "22.11.0", Status: StatusPresent},
+	}
+	if err := printToolSummary(results); err != nil {
+		t.Errorf("printToolSummary = %v, want nil when nothing failed", err)
+	}
+}
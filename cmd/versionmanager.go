@@ -0,0 +1,492 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// VersionManager installs the tool versions pinned in config/tool-versions.
+type VersionManager interface {
+	// Name identifies the manager and how it was installed (asdf-git, asdf-aur, asdf-go, mise).
+	Name() string
+	// Installed reports whether the manager is installed this way.
+	Installed() bool
+	// Setup installs the manager itself.
+	Setup() error
+	// EnsurePlugin installs the plugin for a tool from its source, or fixes an installed
+	// plugin that doesn't match it.
+	EnsurePlugin(plugin PluginSource) error
+	// PluginDir returns the git checkout of the plugin for a tool, or "" when the manager
+	// doesn't need a plugin for it.
+	PluginDir(plugin PluginSource) string
+	// HasVersion reports whether a version of tool is already installed.
+	HasVersion(tool, version string) bool
+	// InstallCommand returns the command that installs a version of tool.
+	InstallCommand(tool, version string) Cmd
+	// Latest returns the newest stable version of tool available upstream.
+	Latest(tool string) (string, error)
+}
+
+// VersionManagers are the names accepted by SetVersionManager besides auto.
+var VersionManagers = []string{"asdf-git", "asdf-aur", "asdf-go", "mise"}
+
+const (
+	// asdfGitVersion is the release cloned by the asdf-git manager (the last shell version).
+	asdfGitVersion = "v0.14.0"
+	// asdfGoVersion is the release downloaded by the asdf-go manager.
+	asdfGoVersion = "v0.16.7"
+)
+
+// versionManager is the manager chosen with --version-manager: a name from VersionManagers,
+// asdf to detect how asdf is installed, or "" and auto to detect any manager.
+var versionManager string
+
+// SetVersionManager selects the version manager by name (see VersionManagers). An empty name
+// falls back to $THUNDERIZE_VERSION_MANAGER and then to auto detection.
+func SetVersionManager(name string) error {
+	if name == "" {
+		name = os.Getenv("THUNDERIZE_VERSION_MANAGER")
+	}
+	if name != "" && name != "auto" && name != "asdf" && !slices.Contains(VersionManagers, name) {
+		return fmt.Errorf("unknown version manager %q (expected auto, asdf, %s)", name, strings.Join(VersionManagers, ", "))
+	}
+	versionManager = name
+	return nil
+}
+
+// allVersionManagers returns every implementation in detection order.
+func allVersionManagers() []VersionManager {
+	return []VersionManager{
+		miseManager{},
+		asdfManager{variant: asdfAUR},
+		asdfManager{variant: asdfGit},
+		asdfManager{variant: asdfGo},
+	}
+}
+
+// GetVersionManager returns the selected version manager. Without an explicit choice the
+// one already installed is used (mise first, then asdf however it was installed), otherwise
+// asdf from the AUR on Arch and the asdf git checkout elsewhere. "asdf" detects only asdf.
+func GetVersionManager() (VersionManager, error) {
+	for _, manager := range allVersionManagers() {
+		if manager.Name() == versionManager {
+			return manager, nil
+		}
+	}
+
+	for _, manager := range allVersionManagers() {
+		if versionManager == "asdf" && manager.Name() == "mise" {
+			continue
+		}
+		if manager.Installed() {
+			return manager, nil
+		}
+	}
+
+	if onArch() {
+		return asdfManager{variant: asdfAUR}, nil
+	}
+	return asdfManager{variant: asdfGit}, nil
+}
+
+// onArch reports whether the system packages come from pacman.
+func onArch() bool {
+	source, err := SystemSource()
+	return err == nil && source == SourcePacman
+}
+
+// ToolVersion is a tool pinned in a tool-versions file.
+type ToolVersion struct {
+	Name     string
+	Versions []string // Every pinned version; the first is the default
+}
+
+// Version returns the default version, or "" when none is pinned.
+func (t ToolVersion) Version() string {
+	if len(t.Versions) == 0 {
+		return ""
+	}
+	return t.Versions[0]
+}
+
+// ToolVersionsPath returns config/tool-versions in the repository.
+func ToolVersionsPath() (string, error) {
+	repoRoot, err := GetRepoRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(repoRoot, "config", "tool-versions"), nil
+}
+
+// ReadToolVersions parses a tool-versions file, skipping blank lines and comments. A tool keeps
+// every version on its line, like asdf, so fallback versions are installed too.
+func ReadToolVersions(path string) ([]ToolVersion, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool-versions: %w", err)
+	}
+
+	var tools []ToolVersion
+	for line := range strings.SplitSeq(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line, _, _ = strings.Cut(line, "#")
+		parts := strings.Fields(line)
+		tools = append(tools, ToolVersion{Name: parts[0], Versions: parts[1:]})
+	}
+	return tools, nil
+}
+
+// asdfVariant is how asdf is installed.
+type asdfVariant string
+
+const (
+	asdfGit asdfVariant = "git" // Shell version cloned into ~/.asdf
+	asdfAUR asdfVariant = "aur" // asdf-vm package from the AUR
+	asdfGo  asdfVariant = "go"  // Go rewrite (0.16+) release binary in ~/.local/bin
+)
+
+// asdfManager drives asdf. The variants differ only in how asdf is installed and found.
+type asdfManager struct {
+	variant asdfVariant
+}
+
+func (m asdfManager) Name() string {
+	return "asdf-" + string(m.variant)
+}
+
+// gitDir returns the asdf-git checkout.
+func (asdfManager) gitDir() string {
+	homeDir, err := GetHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".asdf")
+}
+
+// goBinary returns where the asdf-go manager installs the asdf binary.
+func (asdfManager) goBinary() string {
+	homeDir, err := GetHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".local", "bin", "asdf")
+}
+
+// bin returns the asdf command, which may not be on PATH in a fresh shell.
+func (m asdfManager) bin() string {
+	var path string
+	switch m.variant {
+	case asdfGit:
+		path = filepath.Join(m.gitDir(), "bin", "asdf")
+	case asdfGo:
+		path = m.goBinary()
+	}
+	if _, err := os.Stat(path); path != "" && err == nil {
+		return path
+	}
+	return "asdf"
+}
+
+func (m asdfManager) Installed() bool {
+	switch m.variant {
+	case asdfGit:
+		_, err := os.Stat(filepath.Join(m.gitDir(), "asdf.sh"))
+		return err == nil
+	case asdfAUR:
+		if !CheckCommandExists("pacman") {
+			return false
+		}
+		_, err := runner.Output(Command("pacman", "-Q", "asdf-vm"))
+		return err == nil
+	case asdfGo:
+		// Any other asdf 0.16+ install, however it got on PATH.
+		out, err := runner.Output(Command(m.bin(), "--version"))
+		return err == nil && asdfVersionAtLeast(string(out), 0, 16)
+	}
+	return false
+}
+
+// asdfVersionAtLeast reports whether `asdf --version` output is at least major.minor.
+func asdfVersionAtLeast(output string, major, minor int) bool {
+	var gotMajor, gotMinor int
+	version := strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(output), "asdf version")), "v")
+	if _, err := fmt.Sscanf(version, "%d.%d", &gotMajor, &gotMinor); err != nil {
+		return false
+	}
+	return gotMajor > major || gotMajor == major && gotMinor >= minor
+}
+
+func (m asdfManager) Setup() error {
+	if m.Installed() {
+		Print.Success(fmt.Sprintf("%s already installed!", m.Name()))
+		return nil
+	}
+
+	Print.NewLns(StyleInfoC, fmt.Sprintf("Installing asdf (%s)...", m.variant))
+
+	switch m.variant {
+	case asdfGit:
+		if err := runner.Run(Command("git", "clone", "https://github.com/asdf-vm/asdf.git", m.gitDir(), "--branch", asdfGitVersion)); err != nil {
+			return fmt.Errorf("failed to clone asdf: %w", err)
+		}
+	case asdfAUR:
+		if err := InstallAURHelper(); err != nil {
+			return err
+		}
+		helper, err := bootstrappedAURHelper()
+		if err != nil {
+			return err
+		}
+		if err := runPassthrough(helper, "-S", "--needed", "--noconfirm", "asdf-vm"); err != nil {
+			return fmt.Errorf("failed to install asdf-vm: %w", err)
+		}
+	case asdfGo:
+		url := fmt.Sprintf("https://github.com/asdf-vm/asdf/releases/download/%[1]s/asdf-%[1]s-%[2]s-%[3]s.tar.gz",
+			asdfGoVersion, runtime.GOOS, runtime.GOARCH)
+		dir := filepath.Dir(m.goBinary())
+		if err := ensureDir(dir); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		if err := runner.Run(Command("sh", "-c", fmt.Sprintf("curl -fsSL %s | tar -xz -C %s asdf", shellQuote(url), shellQuote(dir)))); err != nil {
+			return fmt.Errorf("failed to download asdf %s: %w", asdfGoVersion, err)
+		}
+	}
+
+	Print.NewLns(StyleSuccess, "asdf installed successfully!")
+	Print.Warn("Note: Source your shell config or restart your shell to use asdf")
+	return nil
+}
+
+// EnsurePlugin adds a missing plugin, and brings an installed one back in line with its source:
+// a checkout at another ref is updated to it, and one cloned from another URL is removed and
+// added again.
+func (m asdfManager) EnsurePlugin(plugin PluginSource) error {
+	output, err := runner.Output(Command(m.bin(), "plugin", "list"))
+	if err != nil {
+		// asdf exits non-zero when no plugins are installed yet.
+		output = nil
+	}
+	for installed := range strings.SplitSeq(strings.TrimSpace(string(output)), "\n") {
+		if installed == plugin.Name {
+			return m.syncPlugin(plugin)
+		}
+	}
+	return m.addPlugin(plugin)
+}
+
+// addPlugin installs the plugin from its source and checks out its ref.
+func (m asdfManager) addPlugin(plugin PluginSource) error {
+	fmt.Printf("%s Installing asdf plugin: %s %s\n", Dim("→"), plugin.Name, Dim(plugin.String()))
+	args := []string{"plugin", "add", plugin.Name}
+	if plugin.Custom() {
+		args = append(args, plugin.URL)
+	}
+	if err := runner.Run(Command(m.bin(), args...)); err != nil {
+		return fmt.Errorf("failed to install asdf plugin %s: %w", plugin.Name, err)
+	}
+	if plugin.Ref != "" {
+		if err := runner.Run(Command(m.bin(), "plugin", "update", plugin.Name, plugin.Ref)); err != nil {
+			return fmt.Errorf("failed to check out asdf plugin %s at %s: %w", plugin.Name, plugin.Ref, err)
+		}
+	}
+	return verifyInstalledPlugin(m.PluginDir(plugin), plugin)
+}
+
+// syncPlugin fixes an installed plugin that doesn't match its source. Removing a plugin also
+// removes the tool's installed versions, which install dev then reinstalls.
+func (m asdfManager) syncPlugin(plugin PluginSource) error {
+	if !plugin.Custom() {
+		return nil
+	}
+	dir := m.PluginDir(plugin)
+	checkout, mismatch, err := checkPluginCheckout(dir, plugin)
+	if err != nil {
+		return fmt.Errorf("failed to check asdf plugin %s: %w", plugin.Name, err)
+	}
+	if mismatch == "" {
+		return nil
+	}
+
+	fmt.Printf("%s Updating asdf plugin %s: %s\n", Dim("→"), plugin.Name, mismatch)
+	if sameGitURL(checkout.URL, plugin.URL) && plugin.Ref != "" {
+		if err := runner.Run(Command(m.bin(), "plugin", "update", plugin.Name, plugin.Ref)); err != nil {
+			return fmt.Errorf("failed to check out asdf plugin %s at %s: %w", plugin.Name, plugin.Ref, err)
+		}
+		return verifyInstalledPlugin(dir, plugin)
+	}
+
+	if err := runner.Run(Command(m.bin(), "plugin", "remove", plugin.Name)); err != nil {
+		return fmt.Errorf("failed to remove asdf plugin %s: %w", plugin.Name, err)
+	}
+	return m.addPlugin(plugin)
+}
+
+// PluginDir returns the plugin checkout in $ASDF_DATA_DIR, which defaults to ~/.asdf for
+// every variant.
+func (m asdfManager) PluginDir(plugin PluginSource) string {
+	dataDir := os.Getenv("ASDF_DATA_DIR")
+	if dataDir == "" {
+		dataDir = m.gitDir()
+	}
+	return filepath.Join(dataDir, "plugins", plugin.Name)
+}
+
+func (m asdfManager) HasVersion(tool, version string) bool {
+	_, err := runner.Output(Command(m.bin(), "where", tool, version))
+	return err == nil
+}
+
+func (m asdfManager) InstallCommand(tool, version string) Cmd {
+	return Command(m.bin(), "install", tool, version)
+}
+
+func (m asdfManager) Latest(tool string) (string, error) {
+	out, err := runner.Output(Command(m.bin(), "latest", tool))
+	if err != nil {
+		return "", fmt.Errorf("asdf latest %s failed: %w", tool, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// miseManager drives mise, which reads tool-versions files and resolves tools through its
+// own registry, so plugins don't need to be added first.
+type miseManager struct{}
+
+// miseCoreTools are the tools mise installs with its core backends. A plugin for one of them
+// would replace the core backend, so their plugins.txt sources only apply to asdf.
+var miseCoreTools = []string{
+	"bun", "deno", "elixir", "erlang", "go", "golang", "java", "node", "nodejs", "python", "ruby", "rust", "swift", "zig",
+}
+
+// usesPlugin reports whether mise installs the tool from the plugin's source.
+func (miseManager) usesPlugin(plugin PluginSource) bool {
+	return plugin.Custom() && !slices.Contains(miseCoreTools, plugin.Name)
+}
+
+func (miseManager) Name() string { return "mise" }
+
+// bin returns the mise command; the mise.run installer puts it in ~/.local/bin, which may
+// not be on PATH in a fresh shell.
+func (miseManager) bin() string {
+	if CheckCommandExists("mise") {
+		return "mise"
+	}
+	if homeDir, err := GetHomeDir(); err == nil {
+		path := filepath.Join(homeDir, ".local", "bin", "mise")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return "mise"
+}
+
+func (m miseManager) Installed() bool {
+	return CheckCommandExists(m.bin())
+}
+
+func (m miseManager) Setup() error {
+	if m.Installed() {
+		Print.Success("mise already installed!")
+		return nil
+	}
+
+	Print.NewLns(StyleInfoC, "Installing mise...")
+
+	if onArch() {
+		if err := runPassthrough("sudo", "pacman", "-S", "--needed", "--noconfirm", "mise"); err != nil {
+			return fmt.Errorf("failed to install mise: %w", err)
+		}
+	} else if err := runner.Run(Command("sh", "-c", "curl -fsSL https://mise.run | sh")); err != nil {
+		return fmt.Errorf("failed to install mise: %w", err)
+	}
+
+	Print.NewLns(StyleSuccess, "mise installed successfully!")
+	Print.Warn("Note: Source your shell config or restart your shell to use mise")
+	return nil
+}
+
+// EnsurePlugin installs plugins with a source of their own, except for core tools; mise
+// resolves other tools through its core backends and registry. An installed plugin at
+// another ref is updated to it, and one cloned from another URL is reinstalled from its
+// source.
+func (m miseManager) EnsurePlugin(plugin PluginSource) error {
+	if !m.usesPlugin(plugin) {
+		return nil
+	}
+	url := plugin.URL
+	if plugin.Ref != "" {
+		url += "#" + plugin.Ref
+	}
+
+	dir := m.PluginDir(plugin)
+	if _, err := os.Stat(dir); err != nil {
+		fmt.Printf("%s Installing mise plugin: %s %s\n", Dim("→"), plugin.Name, Dim(plugin.String()))
+		if err := runner.Run(Command(m.bin(), "plugins", "install", plugin.Name, url)); err != nil {
+			return fmt.Errorf("failed to install mise plugin %s: %w", plugin.Name, err)
+		}
+		return verifyInstalledPlugin(dir, plugin)
+	}
+
+	checkout, mismatch, err := checkPluginCheckout(dir, plugin)
+	if err != nil {
+		return fmt.Errorf("failed to check mise plugin %s: %w", plugin.Name, err)
+	}
+	if mismatch == "" {
+		return nil
+	}
+
+	fmt.Printf("%s Updating mise plugin %s: %s\n", Dim("→"), plugin.Name, mismatch)
+	c := Command(m.bin(), "plugins", "install", "--force", plugin.Name, url)
+	if sameGitURL(checkout.URL, plugin.URL) && plugin.Ref != "" {
+		c = Command(m.bin(), "plugins", "update", plugin.Name+"#"+plugin.Ref)
+	}
+	if err := runner.Run(c); err != nil {
+		return fmt.Errorf("failed to update mise plugin %s: %w", plugin.Name, err)
+	}
+	return verifyInstalledPlugin(dir, plugin)
+}
+
+// PluginDir returns the plugin checkout in mise's data directory for tools installed from a
+// plugin.
+func (m miseManager) PluginDir(plugin PluginSource) string {
+	if !m.usesPlugin(plugin) {
+		return ""
+	}
+	dataDir := os.Getenv("MISE_DATA_DIR")
+	if dataDir == "" {
+		base := os.Getenv("XDG_DATA_HOME")
+		if base == "" {
+			homeDir, err := GetHomeDir()
+			if err != nil {
+				return ""
+			}
+			base = filepath.Join(homeDir, ".local", "share")
+		}
+		dataDir = filepath.Join(base, "mise")
+	}
+	return filepath.Join(dataDir, "plugins", plugin.Name)
+}
+
+func (m miseManager) HasVersion(tool, version string) bool {
+	_, err := runner.Output(Command(m.bin(), "where", tool+"@"+version))
+	return err == nil
+}
+
+func (m miseManager) InstallCommand(tool, version string) Cmd {
+	return Command(m.bin(), "install", "--yes", tool+"@"+version)
+}
+
+func (m miseManager) Latest(tool string) (string, error) {
+	out, err := runner.Output(Command(m.bin(), "latest", tool))
+	if err != nil {
+		return "", fmt.Errorf("mise latest %s failed: %w", tool, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadToolVersionsKeepsEveryVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool-versions")
+	data := "# pinned tools\nnodejs 22.11.0 20.18.0\npython   3.13.0 # primary\n\nrust\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tools, err := ReadToolVersions(path)
+	if err != nil {
+		t.Fatalf("ReadToolVersions: %v", err)
+	}
+	want := []ToolVersion{
+		{Name: "nodejs", Versions: []string{"22.11.0", "20.18.0"}},
+		{Name: "python", Versions: []string{"3.13.0"}},
+		{Name: "rust", Versions: []string{}},
+	}
+	if !slices.EqualFunc(tools, want, func(a, b ToolVersion) bool {
+		return a.Name == b.Name && slices.Equal(a.Versions, b.Versions)
+	}) {
+		t.Errorf("ReadToolVersions = %v, want %v", tools, want)
+	}
+	if got := tools[0].Version(); got != "22.11.0" {
+		t.Errorf("default nodejs version = %q, want 22.11.0", got)
+	}
+	if got := tools[2].Version(); got != "" {
+		t.Errorf("default rust version = %q, want none", got)
+	}
+}
+
+// fakePlugin answers a version manager's plugin commands and the git queries on its checkout.
+// Installing, updating and removing the plugin changes the checkout's origin and HEAD.
+type fakePlugin struct {
+	origin, head string
+	commit       string // Commit the pinned ref resolves to
+}
+
+func (p *fakePlugin) respond(c Cmd) ([]byte, error) {
+	args := strings.Join(c.Args, " ")
+	switch {
+	case args == "plugin list":
+		return []byte("gleam\n"), nil
+	case strings.HasSuffix(args, "remote get-url origin"):
+		return []byte(p.origin + "\n"), nil
+	case strings.HasSuffix(args, "rev-parse HEAD"):
+		return []byte(p.head + "\n"), nil
+	case strings.Contains(args, "rev-parse --verify"):
+		return []byte(p.commit + "\n"), nil
+	case strings.Contains(args, "update"):
+		p.head = p.commit
+	case strings.Contains(args, "install") || strings.HasPrefix(args, "plugin add"):
+		p.origin, p.head = strings.Split(c.Args[len(c.Args)-1], "#")[0], p.commit
+	}
+	return nil, nil
+}
+
+func TestEnsurePluginFixesInstalledCheckout(t *testing.T) {
+	const url = "https://github.com/asdf-community/asdf-gleam.git"
+	source := PluginSource{Name: "gleam", URL: url, Ref: "v1.0.0"}
+	fakeCommands(t, "mise")
+
+	tests := []struct {
+		name    string
+		manager VersionManager
+		origin  string
+		head    string
+		want    []string
+	}{
+		{"asdf matching", asdfManager{variant: asdfAUR}, url, "bbbb", []string{"plugin list"}},
+		{"asdf other ref", asdfManager{variant: asdfAUR}, url, "aaaa",
+			[]string{"plugin list", "plugin update gleam v1.0.0"}},
+		{"asdf other url", asdfManager{variant: asdfAUR}, "https://github.com/someone/asdf-gleam.git", "aaaa",
+			[]string{"plugin list", "plugin remove gleam", "plugin add gleam " + url, "plugin update gleam v1.0.0"}},
+		{"mise matching", miseManager{}, url, "bbbb", nil},
+		{"mise other ref", miseManager{}, url, "aaaa", []string{"plugins update gleam#v1.0.0"}},
+		{"mise other url", miseManager{}, "https://github.com/someone/asdf-gleam.git", "aaaa",
+			[]string{"plugins install --force gleam " + url + "#v1.0.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataDir := t.TempDir()
+			t.Setenv("ASDF_DATA_DIR", dataDir)
+			t.Setenv("MISE_DATA_DIR", dataDir)
+			if err := os.MkdirAll(filepath.Join(dataDir, "plugins", "gleam"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			plugin := &fakePlugin{origin: tt.origin, head: tt.head, commit: "bbbb"}
+			rec := &RecordingRunner{Respond: plugin.respond}
+			useRunner(t, rec)
+
+			if err := tt.manager.EnsurePlugin(source); err != nil {
+				t.Fatalf("EnsurePlugin: %v", err)
+			}
+
+			var got []string
+			for _, c := range rec.Commands() {
+				if c.Name != "git" {
+					got = append(got, strings.Join(c.Args, " "))
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ran %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiseLeavesCoreToolsToCoreBackends(t *testing.T) {
+	rec := &RecordingRunner{}
+	useRunner(t, rec)
+
+	source := PluginSource{Name: "golang", URL: "https://github.com/asdf-community/asdf-golang.git"}
+	if err := (miseManager{}).EnsurePlugin(source); err != nil {
+		t.Fatalf("EnsurePlugin: %v", err)
+	}
+	if commands := rec.Commands(); len(commands) > 0 {
+		t.Errorf("ran %v for a core tool", commands)
+	}
+	if dir := (miseManager{}).PluginDir(source); dir != "" {
+		t.Errorf("PluginDir = %q for a core tool, want none", dir)
+	}
+	if dir := (asdfManager{variant: asdfAUR}).PluginDir(source); dir == "" {
+		t.Error("asdf has no plugin directory for golang")
+	}
+}
+
+func TestSetPluginRefPinsOneLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugins.txt")
+	data := "# gleam url=https://example.com/gleam.git\n" +
+		"gleam url=https://example.com/asdf-gleam.git # formatter too\n" +
+		"elm url=https://example.com/asdf-elm.git ref=main\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, ref := range map[string]string{"gleam": "bbbb", "elm": "cccc"} {
+		if err := setPluginRef(path, name, ref); err != nil {
+			t.Fatalf("setPluginRef %s: %v", name, err)
+		}
+	}
+	if err := setPluginRef(path, "dprint", "dddd"); err == nil {
+		t.Error("pinned a plugin that isn't listed")
+	}
+
+	got, _ := os.ReadFile(path)
+	want := "# gleam url=https://example.com/gleam.git\n" +
+		"gleam url=https://example.com/asdf-gleam.git ref=bbbb # formatter too\n" +
+		"elm url=https://example.com/asdf-elm.git ref=cccc\n"
+	if string(got) != want {
+		t.Errorf("plugins.txt:\n%s\nwant:\n%s", got, want)
+	}
+}
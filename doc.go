@@ -24,7 +24,7 @@
 // The tool is organized into several key components:
 //
 //   - Config Management: Synchronizes dotfiles between repository and system locations
-//   - Package Management: Handles package installation via pacman, AUR, and asdf
+//   - Package Management: Handles package installation via pacman, AUR, apt, dnf, flatpak and asdf
 //   - Secrets Management: Securely manages API keys and credentials
 //   - System Checks: Validates system requirements and tool availability
 //
@@ -47,6 +47,16 @@
 //	thunderize config list             # Show all available configs
 //	thunderize config validate         # Verify configs exist in repo
 //
+// Configs are embedded in the binary at build time, so a single thunderize binary
+// copied to a new machine can deploy them without a repo checkout. The source is
+// selected with the global --config-source flag:
+//
+//	thunderize config deploy all                              # auto: repo, then embedded
+//	thunderize --config-source embedded config deploy all     # always use embedded configs
+//	thunderize --config-source repo config deploy all         # require the on-disk repo
+//
+// Each deploy reports which source was used. Backups always write to the on-disk repo.
+//
 // Available configurations:
 //   - neovim:     Neovim editor configuration (~/.config/nvim)
 //   - zsh:        Zsh shell configuration (~/.zshrc)
@@ -56,17 +66,170 @@
 //
 // ## Package Installation
 //
-// Install packages on Arch Linux:
+// Install packages:
 //
+//	thunderize install system          # Install the list for this distribution
 //	thunderize install pacman          # Install from official repositories
 //	thunderize install aur             # Install from AUR
-//	thunderize install dev             # Install development tools via asdf
+//	thunderize install flatpak         # Install Flatpak apps (--user, default, or --system)
+//	thunderize install dev             # Install asdf/mise tools and language tools from dev.txt
 //	thunderize install all             # Install everything
 //
+// Install only some sections of a list (the comment headers in packages/*.txt):
+//
+//	thunderize install pacman --section Fonts --section Bluetooth
+//	thunderize install aur --section Applications
+//	thunderize install list-sections   # Show sections with package counts
+//
+// A section must hold packages for the source being installed: naming one that only
+// appears in another source's list is an error listing the sections that source has.
+//
+// Each package source has a backend that queries installed packages, installs, removes
+// and validates names: pacman, the AUR helper, apt, dnf and flatpak. install system
+// picks the backend from /etc/os-release (ID, then ID_LIKE) and installs pacman.txt on
+// Arch-based systems, apt.txt on Debian and Ubuntu, and dnf.txt on Fedora and RHEL;
+// install all on a non-Arch system installs that list and the dev tools, skipping AUR.
+// Any entry can target another backend with source=, e.g. "fd-find source=dnf".
+//
+// Installs validate missing packages first and skip those the backend can't find.
+// The rest install in one transaction; if it fails, the batch is split in halves and
+// retried down to single packages, so one renamed or conflicting package doesn't
+// block the others. Each install ends with a report of installed, skipped and failed
+// packages with the reason for each (the last line pacman, apt, ... printed to
+// stderr), and exits non-zero when a required package wasn't installed.
+//
+// packages/flatpak.txt declares remotes (entries with a url= attribute) and the app IDs
+// to install from them (remote= selects a remote, flathub by default):
+//
+//	flathub url=https://dl.flathub.org/repo/flathub.flatpakrepo
+//	com.google.Chrome profile=flatpak
+//	org.example.App remote=my-remote
+//
+// install flatpak adds missing remotes and installs missing apps at user scope, or
+// system-wide with --system. install all includes it when flatpak is installed. The
+// apps shipped in flatpak.txt use profile=flatpak, so machines that prefer the Flatpak
+// builds of browsers and editors opt in with --profile flatpak.
+//
+// Installed packages are detected from pacman's local database (/var/lib/pacman/local),
+// so packages installed as dependencies, names provided by installed packages (e.g.
+// a listed "sh" satisfied by bash) and groups whose members are all installed are not
+// passed to pacman again. Listed packages that were pulled in as dependencies can be
+// marked as explicitly installed (pacman -D --asexplicit), so they appear in pacman -Qe
+// and are kept when whatever pulled them in is removed:
+//
+//	thunderize install pacman --mark-explicit
+//	thunderize install all --mark-explicit
+//
+// When no AUR helper is installed, install aur (and install all, setup, bootstrap)
+// builds one from the AUR. --aur-helper or $THUNDERIZE_AUR_HELPER picks yay or paru,
+// built from source or as the prebuilt -bin package, optionally pinned to an AUR git
+// commit; yay from source is the default. An explicitly chosen helper is installed even
+// when the other one is present:
+//
+//	thunderize install aur --aur-helper paru-bin
+//	thunderize install aur --aur-helper yay@0a1b2c3d4e5f
+//
+// The AUR repository is cloned into a private temporary directory that is removed
+// afterwards. A pinned commit is checked out and compared with HEAD before building;
+// otherwise the commit being built is printed so it can be pinned. The PKGBUILD is
+// shown for review before makepkg runs unless --noconfirm is set.
+//
+// install dev installs the versions pinned in config/tool-versions with a version
+// manager, setting the manager up first when it's missing. --version-manager or
+// $THUNDERIZE_VERSION_MANAGER selects one per machine:
+//   - asdf-git: the shell version (v0.14.0) cloned into ~/.asdf
+//   - asdf-aur: the asdf-vm package from the AUR
+//   - asdf-go:  the Go rewrite (0.16+) release binary in ~/.local/bin
+//   - mise:     pacman on Arch, the mise.run installer elsewhere; only plugins.txt plugins
+//     for tools without a core backend are added
+//   - asdf:     whichever asdf is installed, else the default below
+//   - auto:     the default; whichever manager is installed (mise, then asdf-aur,
+//     asdf-git, any asdf 0.16+), else asdf-aur on Arch and asdf-git elsewhere
+//
+// So installing asdf-vm from aur.txt before the dev tools (as install all does) makes
+// install dev use it instead of cloning a second asdf.
+//
+//	thunderize install dev --version-manager mise
+//
+// Every version listed for a tool installs on its own, --jobs at a time (2 by default), so
+// one failed build doesn't stop the rest. Versions already installed are skipped. Each
+// install logs to ~/.local/state/thunderize/logs/tools-<timestamp>/<tool>-<version>.log
+// and is killed after --tool-timeout (45m by default, 0 for no limit). A table of every version's
+// status, duration and log follows, and the command fails if any version failed.
+//
+//	thunderize install dev --jobs 4 --tool-timeout 20m
+//
+// Plugins come from the git sources listed in packages/plugins.txt rather than the asdf
+// short-name registry, which doesn't carry every tool. A source follows the plugin's
+// default branch unless ref= pins it to a tag or commit:
+//
+//	<tool> url=<git url> [ref=<branch, tag or commit>]
+//	gleam url=https://github.com/asdf-community/asdf-gleam.git ref=v1.0.0
+//
+// asdf adds a missing plugin from its url (asdf plugin add <tool> <url>) and checks out
+// ref (asdf plugin update <tool> <ref>); mise installs plugins from <url>#<ref>. mise
+// has core backends for golang, python, nodejs, ruby, deno, erlang and elixir, which a
+// plugin would replace, so their sources only apply to asdf. An installed plugin at
+// another commit is updated to ref, and one cloned from another URL is removed and added
+// again (mise reinstalls it with --force); asdf drops the tool's installed versions with
+// the plugin, and they're reinstalled. The checkout's origin and HEAD are compared with
+// the source straight after, and a plugin that fails to install or doesn't match fails
+// its tool. Tools without an entry use the registry. Check installed plugins against
+// their sources at any time:
+//
+//	thunderize tools plugins verify
+//
+// A plugin runs its own scripts on every install, so an entry without ref= runs whatever
+// its default branch holds. Installs and verify warn about unpinned entries; pin them at
+// the commits currently installed, which writes ref=<commit> into the repo's plugins.txt:
+//
+//	thunderize tools plugins pin
+//
+// Check the pinned versions against the latest releases (asdf latest or mise latest)
+// and bump one, which rewrites its line in the repo's config/tool-versions and installs
+// the new version (the latest when no version is given). A tool can list several versions
+// (nodejs 22.11.0 20.18.0); the first is the default, which outdated and bump work on:
+//
+//	thunderize tools outdated
+//	thunderize tools bump golang
+//	thunderize tools bump nodejs 24.8.0
+//
 // Package lists are maintained in the packages/ directory:
 //   - packages/pacman.txt: Official repository packages
 //   - packages/aur.txt:    AUR packages and asdf plugins
+//   - packages/apt.txt:    Debian/Ubuntu packages
+//   - packages/dnf.txt:    Fedora/RHEL packages
+//   - packages/flatpak.txt: Flatpak remotes and apps
 //   - packages/dev.txt:    Language-specific dev tools (pip, cargo, npm, etc.)
+//   - packages/plugins.txt: asdf plugin sources for config/tool-versions
+//
+// List syntax:
+//
+//	# Fonts                                  <- full-line comments start a named section
+//	noto-fonts
+//	ttf-ms-fonts optional source=aur         <- attributes follow the name
+//	nvidia-utils host=desktop arch=x86_64    # trailing comments are ignored
+//
+// Attributes:
+//   - optional:          Install on its own after the rest; a failure only warns
+//   - host=a,b:          Only on these hostnames
+//   - profile=work,home: Only when one of these profiles is active (--profile or $THUNDERIZE_PROFILE)
+//   - arch=x86_64:       Only on these architectures (pacman names)
+//   - source=aur:        Install from another source (pacman, aur, apt, dnf, flatpak) than the list's default
+//
+// Plain one-name-per-line lists remain valid.
+//
+// Each dev.txt entry names its installer with inline attributes:
+//
+//	<name> via=<installer> [pkg=<package>] [bin=<command>]
+//	delve via=go pkg=github.com/go-delve/delve/cmd/dlv@latest bin=dlv
+//
+// Installers: pipx, go (go install), cargo (cargo install), rustup (rustup component
+// add), npm (npm install -g), opam, dotnet (dotnet tool install --global) and pacman.
+// pkg and bin default to the name. Tools whose command is already on PATH (or in
+// ~/go/bin, ~/.cargo/bin, ~/.dotnet/tools, ~/.local/bin) are skipped, tools whose
+// installer is missing are reported as skipped, and a summary of installed, present,
+// skipped and failed tools is printed at the end.
 //
 // Supported languages and tools:
 //   - Python:  black, mypy, ruff, pytest, pylsp
@@ -76,6 +239,97 @@
 //   - .NET:    fsautocomplete, dotnet-script
 //   - LSPs:    bash, yaml, json, markdown, lua, typescript, html, css
 //
+// ## Package List Maintenance
+//
+// Compare the package lists with the installed system:
+//
+//	thunderize packages diff           # Human-readable report
+//	thunderize packages diff --json    # Machine-readable report
+//
+// The report lists packages that are listed but not installed, explicitly installed
+// packages missing from every list (split into repo and foreign/AUR via pacman -Qm),
+// and packages listed in the wrong file (AUR packages in pacman.txt and vice versa).
+// The packages, install and setup commands read the on-disk repo's lists when present,
+// following --config-source, and fall back to the embedded lists, so diff reports
+// against the same lists install uses. Each command resolves the lists once and reads
+// every list, including dev.txt and plugins.txt, from that source.
+//
+// Capture packages installed ad hoc with pacman or yay back into the lists:
+//
+//	thunderize packages capture
+//
+// Explicitly installed packages that are in no list are appended to the on-disk
+// repo's pacman.txt (repo packages) or aur.txt (foreign packages) under an
+// "Uncategorized" section, leaving existing comments and sections untouched.
+//
+// Prune packages that were removed from the lists:
+//
+//	thunderize packages prune          # Show the plan and ask before removing
+//	thunderize packages prune --yes    # Remove without confirmation
+//
+// Every package thunderize installs is recorded in ~/.local/state/thunderize/installed.json.
+// Prune removes recorded packages that no longer appear in any list with the backend
+// that installed them (pacman -Rns, apt, dnf, flatpak). Records of packages that backend
+// no longer has installed are dropped. Core packages (base, linux, pacman, sudo, systemd, the AUR helpers, ...) and anything in the
+// optional packages/protected.txt list are never removed.
+//
+// Lock and verify package versions:
+//
+//	thunderize packages lock           # Write packages.lock from installed versions
+//	thunderize packages verify         # Report drift against packages.lock
+//
+// packages.lock sits at the repo root with one "name version repository" line per
+// listed, installed package (repository is the sync repo such as core or extra, or
+// "aur" for foreign packages). Groups such as base-devel are locked as their installed
+// members, and provided names as the packages providing them. verify reports version
+// changes, locked packages that are no longer installed and installed packages missing
+// from the lock, and exits non-zero when anything drifted.
+//
+// Lint the package lists:
+//
+//	thunderize packages lint
+//
+// Errors: packages listed more than once across files, known conflicting pairs
+// (git-delta/delta-git, yay/yay-bin, ...), AUR entries that exist in a sync
+// repository and repo entries that are neither a sync package nor a group (checked
+// against the local sync databases). Warnings: deprecated packages (exa-git),
+// packages thunderize installs itself (yay, paru), asdf-vm unless the version manager
+// (--version-manager, detected as for install dev) is asdf-aur, and unsorted sections.
+// The command exits non-zero when any errors are found.
+//
+// Validate pacman.txt offline:
+//
+//	thunderize packages validate
+//
+// Every repo entry is checked against the sync databases in /var/lib/pacman/sync
+// (gzip, zstd or bzip2 compressed), read directly without calling pacman or touching
+// the network. Package names, groups and provided names are accepted; unknown entries
+// are reported with their file and line and the closest matching names. install pacman
+// runs the same check before calling pacman and skips unknown packages.
+//
+// ## Offline Reinstalls
+//
+// Build a local package repository from what is already installed:
+//
+//	thunderize packages cache build ~/pkgcache
+//
+// Every listed pacman and AUR package, the installed members of listed groups and
+// their dependencies are looked up at their installed version in the pacman cache
+// (/var/cache/pacman/pkg) and the yay and paru build directories under ~/.cache. The
+// built packages and their signatures are copied into the directory and indexed with
+// repo-add as the thunderize repository. Packages with no cached build are listed so
+// they can be reinstalled before the cache is rebuilt.
+//
+// Reinstall from it without network access:
+//
+//	thunderize install --local-repo ~/pkgcache all
+//
+// pacman and AUR packages are installed with a temporary pacman.conf and database
+// directory that only list the local repository, so AUR packages need no helper or
+// build. Installs are recorded in the system's local database, but the sync databases
+// in /var/lib/pacman/sync are neither refreshed nor touched. install all skips flatpaks
+// and dev tools, which need the network.
+//
 // ## Secrets Management
 //
 // Initialize secrets file from template:
@@ -100,6 +354,25 @@
 //
 // Opens ~/.zsh_secrets in your default $EDITOR.
 //
+// Encrypted configs:
+//
+// Files that can't be committed in plain text (NetworkManager connections, ~/.netrc,
+// tool configs holding API keys) can be stored age-encrypted in the repo by setting
+// Encrypted on their ConfigType. They are decrypted on deploy (written with 0600
+// permissions) and re-encrypted on backup, so plaintext never reaches the repo.
+// 'config validate' fails if an encrypted entry's repo file is plaintext.
+//
+// Keys are read from, in order:
+//   - THUNDERIZE_AGE_KEY_FILE: age identity file (X25519 keys)
+//   - ~/.config/thunderize/age.key: default identity file, when present
+//   - THUNDERIZE_AGE_PASSPHRASE: scrypt passphrase
+//   - An interactive passphrase prompt
+//
+// A prompted passphrase is asked twice before encrypting and reused for the rest of
+// the run once it has decrypted or encrypted a file. Backups skip repo files whose
+// contents haven't changed, and fail rather than overwrite a repo file that the
+// passphrase or key can't decrypt.
+//
 // Security best practices:
 //   - Never commit ~/.zsh_secrets to version control (gitignored)
 //   - Keep only the template (config/zsh_secrets.templ) in git
@@ -117,12 +390,49 @@
 //  2. Package installation (all packages)
 //  3. Config deployment (all configurations)
 //
+// Bootstrap a new machine from a git remote:
+//
+//	thunderize bootstrap <git-url> [--dir ~/dotfiles] [--branch main]
+//
+// This clones the repo (or fast-forwards an existing checkout of the same remote),
+// verifies it contains every package list and config thunderize expects, records
+// its location under ~/.local/state/thunderize/repo, then runs checks, package
+// installation and config deployment from the checkout. Use --skip-checks,
+// --skip-install and --skip-deploy to run only part of the chain.
+//
 // Run system checks only:
 //
 //	thunderize check
 //
 // Verifies availability of required system tools.
 //
+// ## Run Reports
+//
+// Every install, config deploy, setup and bootstrap run saves a report as JSON under
+// ~/.local/state/thunderize/runs/<id>, where the ID is the start time (20060102-150405).
+// Reports record the run's steps with durations, each command that changed the system
+// with its duration and error, every listed package with its status (installed,
+// present, skipped, failed) and reason, the configs deployed (or left unchanged because
+// the target already matched) and the warnings printed.
+// Read-only queries aren't recorded, and dry runs don't save a report.
+//
+//	thunderize report list                    # Saved reports, most recent first
+//	thunderize report show                    # The last run as tables
+//	thunderize report show 20260301-101500 --markdown > setup.md
+//
+// ## Dry Run
+//
+// Preview any command without changing the system:
+//
+//	thunderize --dry-run install all
+//	thunderize --dry-run config deploy all
+//
+// Commands that would install, remove or sync are printed with their working directory
+// and environment instead of running, and file writes report their target. Read-only
+// queries (pacman -Q, asdf plugin list, ...) still run so the plan matches the system.
+// Packages and tools that would be installed are reported as planned, and packages aren't
+// added to the install record.
+//
 // # Cross-Platform Configuration
 //
 // The zshrc configuration (config/zshrc) includes platform detection and
@@ -143,7 +453,7 @@
 //   - Arch:  pacman, AUR helpers (yay, paru)
 //
 // Path management:
-//   - asdf shims for version-managed tools
+//   - asdf shims and mise activation for version-managed tools
 //   - Cargo/Rust binaries (~/.cargo/bin)
 //   - Platform-specific SDK paths (Flutter, Android)
 //   - Language-specific paths (opam, ghcup, dune)
@@ -154,24 +464,53 @@
 //	├── main.go                  # CLI entry point and command definitions
 //	├── doc.go                   # This documentation file
 //	├── cmd/
+//	│   ├── atomic.go           # Crash-safe file and directory writes
+//	│   ├── aurhelper.go        # AUR helper bootstrap (yay/paru, pinning, review)
+//	│   ├── backend.go          # Package backends (pacman, AUR, apt, dnf, flatpak)
+//	│   ├── batch.go            # Batched installs with failure isolation
+//	│   ├── bootstrap.go        # New machine bootstrap from git
+//	│   ├── capture.go          # Capture installed packages into lists
 //	│   ├── checks.go           # System validation checks
 //	│   ├── config.go           # Configuration management
+//	│   ├── crypt.go            # age encryption for configs
+//	│   ├── devtools.go         # Language dev tools (dev.txt)
+//	│   ├── diff.go             # Package lists vs installed system
+//	│   ├── flatpak.go          # Flatpak remotes and apps
+//	│   ├── lint.go             # Package list linting
+//	│   ├── lists.go            # Package list parsing (sections, attributes)
+//	│   ├── localdb.go          # Installed package detection (local database)
+//	│   ├── localrepo.go        # Offline local package repository
+//	│   ├── lock.go             # packages.lock and version drift
 //	│   ├── packages.go         # Package installation logic
+//	│   ├── plugins.go          # Version manager plugin sources
 //	│   ├── printer.go          # Terminal output styling
+//	│   ├── prune.go            # Install record and package pruning
+//	│   ├── report.go           # Run reports (JSON, tables, Markdown)
+//	│   ├── runner.go           # Command runner (exec, dry-run, recording)
 //	│   ├── secrets.go          # Secrets management
+//	│   ├── source.go           # Repo vs embedded config sources
+//	│   ├── state.go            # Persistent state (~/.local/state/thunderize)
+//	│   ├── syncdb.go           # Offline pacman sync database reader
 //	│   ├── sync.go             # File synchronization (rsync)
-//	│   └── utils.go            # Helper utilities
+//	│   ├── tools.go            # Tool version installs, outdated and bumped entries
+//	│   ├── utils.go            # Helper utilities
+//	│   └── versionmanager.go   # Dev tool version managers (asdf, mise)
 //	├── config/
 //	│   ├── nvim/               # Neovim configuration
 //	│   ├── alacritty/          # Alacritty terminal config
 //	│   ├── zshrc               # Zsh shell configuration
 //	│   ├── omp.json            # oh-my-posh prompt theme
-//	│   ├── tool-versions       # asdf tool versions
+//	│   ├── tool-versions       # asdf/mise tool versions
 //	│   └── zsh_secrets.templ # Secrets template
+//	├── packages.lock           # Locked package versions
 //	├── packages/
 //	│   ├── pacman.txt          # Official repo packages
 //	│   ├── aur.txt             # AUR packages
-//	│   └── dev.txt             # Development tools
+//	│   ├── apt.txt             # Debian/Ubuntu packages
+//	│   ├── dnf.txt             # Fedora/RHEL packages
+//	│   ├── flatpak.txt         # Flatpak remotes and apps
+//	│   ├── dev.txt             # Development tools
+//	│   └── plugins.txt         # asdf plugin sources
 //	└── doc/                    # Additional documentation
 //
 // # Configuration System
@@ -184,26 +523,36 @@
 //	    SystemPath string      // Path on system (~ expanded)
 //	    IsFile     bool        // Single file vs directory
 //	    Excludes   []string    // rsync exclude patterns
+//	    Encrypted  bool        // age-encrypted in repo (files only)
 //	}
 //
-// All configs are synchronized using rsync with:
+// Deploys read from the on-disk repo or the embedded config tree (see --config-source).
+// Directory configs are synchronized using rsync with:
 //   - Archive mode (-a): Preserves permissions and timestamps
-//   - Delete flag: Removes files not in source (directories only)
+//   - Delete flag: Removes files not in source
 //   - Default excludes: .git, *.swp, *.swo
 //   - Custom excludes: Per-config patterns
 //
+// Writes are crash-safe:
+//   - Single files are written to a temp file in the target directory, fsynced,
+//     given the source's mode and renamed into place
+//   - Directories are rsynced into a staging copy next to the target, which is
+//     swapped in only after rsync succeeds, so a failure leaves the previous tree intact
+//   - Symlinked targets are followed so the link itself is kept
+//
 // # Package Installation
 //
 // Package installation follows this workflow:
 //
-//  1. Read package list from embedded filesystem
-//  2. Filter out comments and empty lines
+//  1. Read package lists from the repo, or the embedded filesystem (see --config-source)
+//  2. Parse sections and attributes, keeping entries that apply to this machine
+//     and routing each to its source (list default or source=)
 //  3. Validate package manager availability
 //  4. Install packages with appropriate command:
 //     - pacman: sudo pacman -S --needed
 //     - AUR:    yay -S --needed (or paru)
-//     - asdf:   asdf plugin add && asdf install
-//     - dev:    Tool-specific installers (pip, cargo, npm, etc.)
+//     - asdf:   asdf plugin add && asdf install (or mise install)
+//     - dev:    Tool-specific installers (pipx, go, cargo, npm, opam, dotnet)
 //
 // The --needed flag prevents reinstallation of up-to-date packages.
 //
@@ -232,11 +581,18 @@
 //   - Use early returns to reduce nesting
 //   - Preserve error chains with %w formatting
 //   - Document all exported functions
+//   - Run external commands through the package Runner (Command, runner.Run) rather
+//     than os/exec, so tests can swap in a RecordingRunner with SetRunner
 //
 // # Examples
 //
 // Initial system setup on new machine:
 //
+//	# Clone, verify and set up in one step
+//	thunderize bootstrap <repo-url> --dir ~/dotfiles
+//
+// Or step by step:
+//
 //	# Clone repository
 //	git clone <repo-url> ~/dotfiles
 //	cd ~/dotfiles
@@ -291,6 +647,13 @@
 //   - EDITOR:     		Used for 'secrets edit' command
 //   - HOME:       		User home directory (standard)
 //   - ASDF_DATA_DIR: 	Custom asdf data directory (optional)
+//   - THUNDERIZE_AGE_KEY_FILE: age identity file for encrypted configs
+//   - THUNDERIZE_AGE_PASSPHRASE: Passphrase for encrypted configs (instead of a key file)
+//   - THUNDERIZE_AUR_HELPER: AUR helper to bootstrap (yay, paru, yay-bin, paru-bin, @<commit> to pin)
+//   - THUNDERIZE_PROFILE: Comma-separated profiles for profile= list conditions
+//   - THUNDERIZE_REPO: Repository root, overriding the binary location and bootstrap record
+//   - THUNDERIZE_VERSION_MANAGER: Dev tool version manager (auto, asdf, asdf-git, asdf-aur, asdf-go, mise)
+//   - XDG_STATE_HOME: 	Base for thunderize state (default ~/.local/state)
 //
 // # Platform-Specific Notes
 //
@@ -301,9 +664,13 @@
 //
 // Arch Linux:
 //   - Base system with pacman required
-//   - AUR helper (yay or paru) recommended
+//   - AUR helper (yay or paru) recommended; bootstrapped with review when missing
 //   - sudo access needed for package installation
 //
+// Debian, Ubuntu, Fedora and RHEL:
+//   - System packages install from apt.txt or dnf.txt with install system
+//   - AUR packages and pacman-specific commands (packages lock, validate) are unavailable
+//
 // # See Also
 //
 // Related documentation:
@@ -3,8 +3,11 @@ module github.com/desertthunder/installer
 go 1.24.5
 
 require (
+	filippo.io/age v1.2.1
 	github.com/charmbracelet/lipgloss v1.1.0
+	github.com/klauspost/compress v1.18.0
 	github.com/urfave/cli/v3 v3.4.1
+	golang.org/x/term v0.21.0
 )
 
 require (
@@ -19,6 +22,7 @@ require (
 	github.com/muesli/termenv v0.16.0 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
+	golang.org/x/crypto v0.24.0 // indirect
 	golang.org/x/exp v0.0.0-20231006140011-7918f672742d // indirect
 	golang.org/x/sys v0.30.0 // indirect
 )
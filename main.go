@@ -13,41 +13,358 @@ import (
 //go:embed packages
 var PackageLists embed.FS
 
+//go:embed config
+var ConfigFiles embed.FS
+
+// Flags shared by several commands are built per command, since a flag holds the value
+// parsed for the command it belongs to.
+
+// sectionFlag limits an install to named sections of the package lists.
+func sectionFlag() cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:    "section",
+		Aliases: []string{"group"},
+		Usage:   "Only install packages from this section (repeatable, see 'install list-sections')",
+	}
+}
+
+// markExplicitFlag marks listed packages installed as dependencies as explicitly installed.
+func markExplicitFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "mark-explicit",
+		Usage: "Mark listed packages installed as dependencies as explicit (pacman -D --asexplicit)",
+	}
+}
+
+// versionManagerFlag selects the dev tool version manager.
+func versionManagerFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "version-manager",
+		Usage: "Dev tool version manager: auto, asdf, asdf-git, asdf-aur, asdf-go or mise (default $THUNDERIZE_VERSION_MANAGER or auto)",
+	}
+}
+
+// bootstrapFlags choose the AUR helper and version manager installs set up when they're
+// missing, followed by extra flags for the command.
+func bootstrapFlags(extra ...cli.Flag) []cli.Flag {
+	return append(extra,
+		&cli.StringFlag{
+			Name:  "aur-helper",
+			Usage: "AUR helper to bootstrap: yay, paru, yay-bin or paru-bin, optionally pinned with @<commit> (default $THUNDERIZE_AUR_HELPER or yay)",
+		},
+		&cli.BoolFlag{
+			Name:  "noconfirm",
+			Usage: "Build the AUR helper without showing its PKGBUILD for review",
+		},
+		versionManagerFlag(),
+		&cli.IntFlag{
+			Name:  "jobs",
+			Usage: "Number of tool versions to install at once",
+			Value: cmd.DefaultToolJobs,
+		},
+		&cli.DurationFlag{
+			Name:  "tool-timeout",
+			Usage: "Kill a tool version install that runs longer than this (0 for no limit)",
+			Value: cmd.DefaultToolTimeout,
+		},
+	)
+}
+
+// setBootstrapOptions applies the bootstrapFlags of c.
+func setBootstrapOptions(c *cli.Command) error {
+	cmd.SetNoConfirm(c.Bool("noconfirm"))
+	cmd.SetToolInstallOptions(c.Int("jobs"), c.Duration("tool-timeout"))
+	if err := cmd.SetVersionManager(c.String("version-manager")); err != nil {
+		return err
+	}
+	return cmd.SetAURHelper(c.String("aur-helper"))
+}
+
+// reported records the run of an install or deploy command as a report (see 'report show').
+func reported(action cli.ActionFunc) cli.ActionFunc {
+	return func(ctx context.Context, c *cli.Command) error {
+		return cmd.RecordRun(c.FullName(), func() error { return action(ctx, c) })
+	}
+}
+
 func main() {
+	cmd.SetEmbeddedConfigs(ConfigFiles)
+
 	root := &cli.Command{
 		Name:  "thunderize",
 		Usage: "Setup and manage an Arch Linux development environment",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "config-source",
+				Usage: "Where to read configs from: auto (repo, then embedded), repo, or embedded",
+				Value: string(cmd.ConfigSourceAuto),
+			},
+			&cli.StringSliceFlag{
+				Name:  "profile",
+				Usage: "Active profiles for profile= conditions in package lists (default $THUNDERIZE_PROFILE)",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Print the commands and file writes that would run without changing anything",
+			},
+		},
+		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+			cmd.SetDryRun(c.Bool("dry-run"))
+			cmd.SetProfiles(c.StringSlice("profile"))
+			return ctx, cmd.SetConfigSource(c.String("config-source"))
+		},
 		Commands: []*cli.Command{
 			{
 				Name:  "install",
 				Usage: "Install packages",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "local-repo",
+						Usage: "Install pacman and AUR packages from a repository built by 'packages cache build', without network access",
+					},
+				},
+				Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+					return ctx, cmd.SetLocalRepo(c.String("local-repo"))
+				},
 				Commands: []*cli.Command{
 					{
 						Name:  "pacman",
 						Usage: "Install packages from official repositories",
-						Action: func(ctx context.Context, c *cli.Command) error {
-							return cmd.InstallPacmanPackages(PackageLists)
-						},
+						Flags: []cli.Flag{sectionFlag(), markExplicitFlag()},
+						Action: reported(func(ctx context.Context, c *cli.Command) error {
+							cmd.SetMarkExplicit(c.Bool("mark-explicit"))
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.InstallPacmanPackages(lists, c.StringSlice("section")...)
+						}),
+					},
+					{
+						Name:  "system",
+						Usage: "Install the package list for this distribution (pacman, apt or dnf)",
+						Flags: []cli.Flag{sectionFlag(), markExplicitFlag()},
+						Action: reported(func(ctx context.Context, c *cli.Command) error {
+							cmd.SetMarkExplicit(c.Bool("mark-explicit"))
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.InstallSystemPackages(lists, c.StringSlice("section")...)
+						}),
 					},
 					{
 						Name:  "aur",
 						Usage: "Install packages from AUR",
-						Action: func(ctx context.Context, c *cli.Command) error {
-							return cmd.InstallAURPackages(PackageLists)
+						Flags: bootstrapFlags(sectionFlag(), markExplicitFlag()),
+						Action: reported(func(ctx context.Context, c *cli.Command) error {
+							cmd.SetMarkExplicit(c.Bool("mark-explicit"))
+							if err := setBootstrapOptions(c); err != nil {
+								return err
+							}
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.InstallAURPackages(lists, c.StringSlice("section")...)
+						}),
+					},
+					{
+						Name:  "flatpak",
+						Usage: "Add remotes and install apps from packages/flatpak.txt",
+						Flags: []cli.Flag{
+							sectionFlag(),
+							&cli.BoolFlag{
+								Name:  "user",
+								Usage: "Install for the current user (default)",
+							},
+							&cli.BoolFlag{
+								Name:  "system",
+								Usage: "Install system-wide",
+							},
 						},
+						Action: reported(func(ctx context.Context, c *cli.Command) error {
+							if c.Bool("user") && c.Bool("system") {
+								return fmt.Errorf("--user and --system are mutually exclusive")
+							}
+							scope := cmd.FlatpakUser
+							if c.Bool("system") {
+								scope = cmd.FlatpakSystem
+							}
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.InstallFlatpakPackages(lists, scope, c.StringSlice("section")...)
+						}),
 					},
 					{
-						Name:  "dev",
-						Usage: "Install development tools via asdf",
+						Name:  "list-sections",
+						Usage: "List package list sections with package counts",
 						Action: func(ctx context.Context, c *cli.Command) error {
-							return cmd.InstallDevTools()
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.ListSections(lists)
 						},
 					},
+					{
+						Name:  "dev",
+						Usage: "Install development tools via asdf or mise and language tools from dev.txt",
+						Flags: bootstrapFlags(),
+						Action: reported(func(ctx context.Context, c *cli.Command) error {
+							if err := setBootstrapOptions(c); err != nil {
+								return err
+							}
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							if err := cmd.InstallDevTools(lists); err != nil {
+								return err
+							}
+							cmd.Print.Info()
+							return cmd.InstallDevPackages(lists)
+						}),
+					},
 					{
 						Name:  "all",
 						Usage: "Install all packages (pacman, AUR, and dev tools)",
+						Flags: bootstrapFlags(markExplicitFlag()),
+						Action: reported(func(ctx context.Context, c *cli.Command) error {
+							cmd.SetMarkExplicit(c.Bool("mark-explicit"))
+							if err := setBootstrapOptions(c); err != nil {
+								return err
+							}
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.InstallAllPackages(lists)
+						}),
+					},
+				},
+			},
+			{
+				Name:  "packages",
+				Usage: "Inspect and maintain package lists",
+				Commands: []*cli.Command{
+					{
+						Name:  "diff",
+						Usage: "Compare package lists with installed packages",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "json",
+								Usage: "Print the difference as JSON",
+							},
+						},
+						Action: func(ctx context.Context, c *cli.Command) error {
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.ShowPackageDiff(lists, c.Bool("json"))
+						},
+					},
+					{
+						Name:  "capture",
+						Usage: "Add explicitly installed packages missing from the lists",
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return cmd.CapturePackages()
+						},
+					},
+					{
+						Name:  "prune",
+						Usage: "Remove packages installed by thunderize that are no longer listed",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:    "yes",
+								Aliases: []string{"y"},
+								Usage:   "Remove without asking for confirmation",
+							},
+						},
+						Action: func(ctx context.Context, c *cli.Command) error {
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.PrunePackages(lists, c.Bool("yes"))
+						},
+					},
+					{
+						Name:  "lock",
+						Usage: "Record installed versions of listed packages in packages.lock",
 						Action: func(ctx context.Context, c *cli.Command) error {
-							return cmd.InstallAllPackages(PackageLists)
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.LockPackages(lists)
+						},
+					},
+					{
+						Name:  "verify",
+						Usage: "Report version drift against packages.lock",
+						Action: func(ctx context.Context, c *cli.Command) error {
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.VerifyPackages(lists)
+						},
+					},
+					{
+						Name:  "lint",
+						Usage: "Check package lists for duplicates, conflicts and misplaced packages",
+						Flags: []cli.Flag{versionManagerFlag()},
+						Action: func(ctx context.Context, c *cli.Command) error {
+							if err := cmd.SetVersionManager(c.String("version-manager")); err != nil {
+								return err
+							}
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.ShowLint(lists)
+						},
+					},
+					{
+						Name:  "validate",
+						Usage: "Check pacman.txt against the local sync databases without network access",
+						Action: func(ctx context.Context, c *cli.Command) error {
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.ValidatePackages(lists)
+						},
+					},
+					{
+						Name:  "cache",
+						Usage: "Manage an offline package repository",
+						Commands: []*cli.Command{
+							{
+								Name:  "build",
+								Usage: "Collect built packages for every listed package into a local repository",
+								Arguments: []cli.Argument{
+									&cli.StringArg{
+										Name:      "dir",
+										UsageText: "Repository directory",
+									},
+								},
+								Action: func(ctx context.Context, c *cli.Command) error {
+									dir := c.StringArg("dir")
+									if dir == "" {
+										return fmt.Errorf("a repository directory is required")
+									}
+									lists, err := cmd.ResolvePackageLists(PackageLists)
+									if err != nil {
+										return err
+									}
+									return cmd.BuildPackageCache(lists, dir)
+								},
+							},
 						},
 					},
 				},
@@ -65,7 +382,7 @@ func main() {
 								UsageText: "Config name (or 'all' for all configs)",
 							},
 						},
-						Action: func(ctx context.Context, c *cli.Command) error {
+						Action: reported(func(ctx context.Context, c *cli.Command) error {
 							name := c.String("name")
 							if name == "" {
 								name = "all"
@@ -74,7 +391,7 @@ func main() {
 								return cmd.DeployAllConfigs()
 							}
 							return cmd.DeployConfig(name)
-						},
+						}),
 					},
 					{
 						Name:  "backup",
@@ -115,24 +432,185 @@ func main() {
 			{
 				Name:  "setup",
 				Usage: "Run full system setup (checks, packages, and configs)",
-				Action: func(ctx context.Context, c *cli.Command) error {
-					if err := cmd.RunSystemChecks(); err != nil {
+				Flags: bootstrapFlags(),
+				Action: reported(func(ctx context.Context, c *cli.Command) error {
+					if err := setBootstrapOptions(c); err != nil {
+						return err
+					}
+					lists, err := cmd.ResolvePackageLists(PackageLists)
+					if err != nil {
+						return err
+					}
+					if err := cmd.RunStep("checks", cmd.RunSystemChecks); err != nil {
 						return err
 					}
 
 					cmd.Print.Info()
-					if err := cmd.InstallAllPackages(PackageLists); err != nil {
+					if err := cmd.RunStep("packages", func() error { return cmd.InstallAllPackages(lists) }); err != nil {
 						return err
 					}
 
 					cmd.Print.Info()
-					if err := cmd.DeployAllConfigs(); err != nil {
+					if err := cmd.RunStep("configs", cmd.DeployAllConfigs); err != nil {
 						return err
 					}
 
 					cmd.Print.Info()
 					cmd.Print.Success("System setup completed successfully!")
 					return nil
+				}),
+			},
+			{
+				Name:      "bootstrap",
+				Usage:     "Clone a dotfiles repo and run full setup from it",
+				ArgsUsage: "<git-url>",
+				Arguments: []cli.Argument{
+					&cli.StringArg{
+						Name:      "url",
+						UsageText: "Git URL of the dotfiles repo",
+					},
+				},
+				Flags: bootstrapFlags(
+					&cli.StringFlag{
+						Name:  "dir",
+						Usage: "Checkout location",
+						Value: "~/dotfiles",
+					},
+					&cli.StringFlag{
+						Name:  "branch",
+						Usage: "Branch to check out (defaults to the remote's default branch)",
+					},
+					&cli.BoolFlag{
+						Name:  "skip-checks",
+						Usage: "Skip system checks",
+					},
+					&cli.BoolFlag{
+						Name:  "skip-install",
+						Usage: "Skip package installation",
+					},
+					&cli.BoolFlag{
+						Name:  "skip-deploy",
+						Usage: "Skip config deployment",
+					},
+				),
+				Action: reported(func(ctx context.Context, c *cli.Command) error {
+					if err := setBootstrapOptions(c); err != nil {
+						return err
+					}
+					url := c.StringArg("url")
+					if url == "" {
+						return fmt.Errorf("missing git URL")
+					}
+					return cmd.Bootstrap(cmd.BootstrapOptions{
+						URL:         url,
+						Dir:         c.String("dir"),
+						Branch:      c.String("branch"),
+						SkipChecks:  c.Bool("skip-checks"),
+						SkipInstall: c.Bool("skip-install"),
+						SkipDeploy:  c.Bool("skip-deploy"),
+					})
+				}),
+			},
+			{
+				Name:  "tools",
+				Usage: "Inspect and update the dev tool versions in config/tool-versions",
+				Flags: []cli.Flag{versionManagerFlag()},
+				Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+					return ctx, cmd.SetVersionManager(c.String("version-manager"))
+				},
+				Commands: []*cli.Command{
+					{
+						Name:  "outdated",
+						Usage: "List pinned tool versions with newer releases",
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return cmd.ShowOutdatedTools()
+						},
+					},
+					{
+						Name:  "bump",
+						Usage: "Pin a tool to a new version (the latest by default) and install it",
+						Arguments: []cli.Argument{
+							&cli.StringArg{
+								Name:      "tool",
+								UsageText: "Tool name in config/tool-versions",
+							},
+							&cli.StringArg{
+								Name:      "version",
+								UsageText: "Version to pin (defaults to the latest)",
+							},
+						},
+						Action: reported(func(ctx context.Context, c *cli.Command) error {
+							tool := c.StringArg("tool")
+							if tool == "" {
+								return fmt.Errorf("missing tool name")
+							}
+							lists, err := cmd.ResolvePackageLists(PackageLists)
+							if err != nil {
+								return err
+							}
+							return cmd.BumpTool(lists, tool, c.StringArg("version"))
+						}),
+					},
+					{
+						Name:  "plugins",
+						Usage: "Check version manager plugins against packages/plugins.txt",
+						Commands: []*cli.Command{
+							{
+								Name:  "verify",
+								Usage: "Check that installed plugins come from the git URL and ref in packages/plugins.txt",
+								Action: func(ctx context.Context, c *cli.Command) error {
+									lists, err := cmd.ResolvePackageLists(PackageLists)
+									if err != nil {
+										return err
+									}
+									return cmd.ShowPluginVerify(lists)
+								},
+							},
+							{
+								Name:  "pin",
+								Usage: "Pin plugins without a ref in packages/plugins.txt at their installed commits",
+								Action: func(ctx context.Context, c *cli.Command) error {
+									lists, err := cmd.ResolvePackageLists(PackageLists)
+									if err != nil {
+										return err
+									}
+									return cmd.PinPlugins(lists)
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:  "report",
+				Usage: "Show reports of past install and deploy runs",
+				Commands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List saved run reports, most recent first",
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return cmd.ShowReports()
+						},
+					},
+					{
+						Name:  "show",
+						Usage: "Show a run report",
+						Arguments: []cli.Argument{
+							&cli.StringArg{
+								Name:      "id",
+								UsageText: "Report ID (or 'last' for the most recent run)",
+							},
+						},
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "markdown",
+								Usage: "Render the report as Markdown",
+							},
+						},
+						Action: func(ctx context.Context, c *cli.Command) error {
+							return cmd.ShowReport(c.StringArg("id"), c.Bool("markdown"))
+						},
+					},
 				},
 			},
 			{